@@ -0,0 +1,120 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// --- Admin Endpoints ---
+// Admin endpoints are disabled unless ADMIN_TOKEN is set; callers send it as a bearer token.
+var AdminToken = getEnv("ADMIN_TOKEN", "")
+
+func adminOnly(method string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if AdminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(AdminToken)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != method {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func reloadRoutingHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := loadRouting()
+	if err != nil {
+		log.Printf("[WARN] Routing reload failed: %v", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"status": "error", "error": err.Error()})
+		return
+	}
+	log.Printf("[INFO] Reloaded %d project routes", n)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "reloaded", "routes": n})
+}
+
+// Maintenance mode: events are acknowledged and counted but not forwarded
+var (
+	forwardingPaused atomic.Bool
+	metricPaused     = expvar.NewInt("events_paused")
+)
+
+// forwardingHandler toggles maintenance mode, e.g. POST {"enabled": false}.
+func forwardingHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, `Expected {"enabled": true|false}`, http.StatusBadRequest)
+		return
+	}
+	forwardingPaused.Store(!*req.Enabled)
+	log.Printf("[INFO] Forwarding enabled: %t", *req.Enabled)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"forwarding": *req.Enabled})
+}
+
+// debounceHandler dumps the anti-spam state: issue IDs and when they were last seen.
+func debounceHandler(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	entries := make(map[string]string, len(lastUpdated))
+	for id, ts := range lastUpdated {
+		entries[id] = time.Unix(ts, 0).UTC().Format(time.RFC3339)
+	}
+	mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(entries), "last_updated": entries})
+}
+
+// DEBUG_ENDPOINTS exposes setup helpers that need no admin token
+var DebugEndpoints = getEnvBool("DEBUG_ENDPOINTS", false)
+
+// signatureTestHandler returns the signature Plane should send for the request
+// body under the secret given in X-Webhook-Secret. The configured secret is
+// never used, so the endpoint cannot sign arbitrary payloads.
+func signatureTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	secret := r.Header.Get("X-Webhook-Secret")
+	if secret == "" {
+		http.Error(w, "Missing X-Webhook-Secret header", http.StatusBadRequest)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	expected := computeSignature(secret, body)
+	res := map[string]interface{}{"algorithm": SignatureAlgo, "signature": expected, "body_bytes": len(body)}
+	if got := r.Header.Get("x-plane-signature"); got != "" {
+		res["matches"] = hmac.Equal([]byte(got), []byte(expected))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func registerAdminRoutes() {
+	if DebugEndpoints {
+		log.Println("[WARN] DEBUG_ENDPOINTS enabled: /debug/signature is exposed")
+		http.HandleFunc("/debug/signature", signatureTestHandler)
+	}
+	http.HandleFunc("/admin/debounce", adminOnly(http.MethodGet, debounceHandler))
+	http.HandleFunc("/admin/reload-routing", adminOnly(http.MethodPost, reloadRoutingHandler))
+	http.HandleFunc("/admin/forwarding", adminOnly(http.MethodPost, forwardingHandler))
+}
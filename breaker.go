@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"expvar"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Circuit Breaker ---
+var (
+	BreakerThreshold = getEnvInt("CIRCUIT_BREAKER_THRESHOLD", 5) // 0 disables the breaker
+	BreakerCooldown  = getEnvDuration("CIRCUIT_BREAKER_COOLDOWN", time.Minute)
+)
+
+// Exposed on /debug/vars
+var (
+	metricDelivered      = expvar.NewInt("discord_deliveries_ok")
+	metricFailed         = expvar.NewInt("discord_deliveries_failed")
+	metricSkippedBreaker = expvar.NewInt("discord_deliveries_skipped_circuit_open")
+)
+
+// circuitBreaker stops delivery attempts for a cooldown after repeated failures.
+// Once the cooldown passes a single failure reopens it until a success resets it.
+type circuitBreaker struct {
+	target    string
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// Each webhook has its own breaker so one dead target does not stop the others
+var (
+	breakers   = map[string]*circuitBreaker{}
+	breakersMu sync.Mutex
+)
+
+// breakerFor returns the breaker of the webhook target belongs to; message
+// edit URLs share the breaker of their webhook.
+func breakerFor(target string) *circuitBreaker {
+	if i := strings.Index(target, "/messages/"); i > 0 {
+		target = target[:i]
+	}
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[target]
+	if !ok {
+		b = &circuitBreaker{target: target}
+		breakers[target] = b
+	}
+	return b
+}
+
+// openCircuits counts webhooks whose circuit is currently open.
+func openCircuits() int {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	n := 0
+	for _, b := range breakers {
+		if b.isOpen() {
+			n++
+		}
+	}
+	return n
+}
+
+func init() {
+	expvar.Publish("discord_circuits_open", expvar.Func(func() interface{} {
+		return openCircuits()
+	}))
+}
+
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().Before(b.openUntil)
+}
+
+func (b *circuitBreaker) allow() bool {
+	if b.isOpen() {
+		metricSkippedBreaker.Add(1)
+		return false
+	}
+	return true
+}
+
+// record feeds a delivery outcome to the breaker; only outages (network
+// errors, 5xx) count as failures.
+func (b *circuitBreaker) record(healthy bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if healthy {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if BreakerThreshold > 0 && b.failures >= BreakerThreshold {
+		b.openUntil = time.Now().Add(BreakerCooldown)
+		log.Printf("[WARN] Discord circuit for %s open for %s after %d consecutive failures",
+			redactWebhook(b.target), BreakerCooldown, b.failures)
+	}
+}
+
+// postTracked posts JSON to a non-Discord endpoint through its circuit breaker
+// and the delivery metrics. A response for which gone reports true disables
+// the endpoint until restart, like a deleted Discord webhook.
+func postTracked(client *http.Client, kind, target string, body []byte, gone func(*http.Response) bool) bool {
+	if _, disabled := disabledWebhooks.Load(target); disabled {
+		return false
+	}
+	breaker := breakerFor(target)
+	if !breaker.allow() {
+		log.Printf("[WARN] Circuit open for %s %s, dropping delivery", kind, redactWebhook(target))
+		return false
+	}
+	resp, err := client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[WARN] Sending to %s: %v", kind, err)
+		breaker.record(false)
+		metricFailed.Add(1)
+		return false
+	}
+	resp.Body.Close()
+	breaker.record(resp.StatusCode < 500)
+	if gone != nil && gone(resp) {
+		log.Printf("[WARN] %s %s returned %s: disabling it until restart", kind, redactWebhook(target), resp.Status)
+		disabledWebhooks.Store(target, resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		log.Printf("[WARN] %s returned %s", kind, resp.Status)
+		metricFailed.Add(1)
+		return false
+	}
+	metricDelivered.Add(1)
+	return true
+}
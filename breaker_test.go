@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBreakerPerTarget(t *testing.T) {
+	prev := BreakerThreshold
+	BreakerThreshold = 1
+	t.Cleanup(func() { BreakerThreshold = prev })
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer up.Close()
+
+	failed, delivered := metricFailed.Value(), metricDelivered.Value()
+	callWebhookOnce(http.MethodPost, down.URL, nil, []byte(`{}`))
+	if !breakerFor(down.URL).isOpen() {
+		t.Fatal("circuit of failing webhook is closed")
+	}
+	if breakerFor(up.URL).isOpen() {
+		t.Fatal("circuit of healthy webhook opened")
+	}
+	callWebhookOnce(http.MethodPost, up.URL, nil, []byte(`{}`))
+	if got := metricFailed.Value() - failed; got != 1 {
+		t.Errorf("failed deliveries = %d, want 1", got)
+	}
+	if got := metricDelivered.Value() - delivered; got != 1 {
+		t.Errorf("delivered = %d, want 1", got)
+	}
+}
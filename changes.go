@@ -0,0 +1,283 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// --- Issue Field Changes ---
+
+// Merge a multi-field save into one embed; when off only the first activity is shown
+var MergeActivities = getEnvBool("MERGE_ACTIVITIES", true)
+
+// Describe updates with Plane's own activity text, e.g. "changed the state to Done"
+var UseActivityText = getEnvBool("USE_ACTIVITY_TEXT", false)
+
+// Skip updates whose old and new values are the same
+var SkipNoopUpdates = getEnvBool("SKIP_NOOP_UPDATES", true)
+
+// Forward cover image changes, showing the new cover as the embed image
+var NotifyCoverImage = getEnvBool("NOTIFY_COVER_IMAGE", false)
+
+// Whitelist of issue fields whose updates are forwarded
+var trackedFields = map[string]bool{
+	"name":           true,
+	"priority":       true,
+	"state":          true,
+	"state_id":       true,
+	"assignee_ids":   true,
+	"target_date":    true,
+	"parent":         true,
+	"estimate_point": true,
+	"module_id":      true,
+	"module":         true,
+	"project_id":     true,
+	"project":        true,
+	"is_draft":       true,
+}
+
+func init() {
+	for rel := range relationVerbs {
+		trackedFields[rel] = true
+	}
+	if NotifyCoverImage {
+		trackedFields["cover_image"] = true
+	}
+}
+
+// Board reordering updates these; they are ignored without logging
+var orderingFields = map[string]bool{
+	"sort_order": true,
+	"sequence":   true,
+	"order":      true,
+	"position":   true,
+}
+
+// isReorder reports whether every activity only changes an ordering field.
+func isReorder(activities []map[string]interface{}) bool {
+	for _, a := range activities {
+		if field, _ := a["field"].(string); !orderingFields[field] {
+			return false
+		}
+	}
+	return len(activities) > 0
+}
+
+// fieldChange is one update activity normalized for display.
+type fieldChange struct {
+	label      string // display name, or the raw field for names and relations
+	oldV, newV string
+	custom     bool
+	pastDue    bool
+	resolved   string // time to completion when the issue moved to a completed state
+	image      string // new cover image URL
+	diff       string // who was assigned or unassigned, replacing the old → new layout
+	noop       bool   // old and new values are equal, raw or after normalization
+	activity   map[string]interface{}
+}
+
+// activityList returns the payload's activities; Plane sends either one
+// object or, for a multi-field save, an array of them.
+func activityList(raw interface{}) []map[string]interface{} {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var list []map[string]interface{}
+		for _, a := range v {
+			if m, ok := a.(map[string]interface{}); ok {
+				list = append(list, m)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// isPastDue reports whether a due date lies before today in TIMEZONE.
+func isPastDue(value string) bool {
+	due, ok := parseDate(value)
+	if !ok {
+		return false
+	}
+	return due.Format("2006-01-02") < time.Now().In(location).Format("2006-01-02")
+}
+
+// describeChange normalizes an update activity, reporting false for fields
+// that are not tracked.
+func describeChange(data, activity map[string]interface{}) (fieldChange, bool) {
+	field := fmt.Sprintf("%v", activity["field"])
+	custom := isCustomField(field)
+	if !trackedFields[field] && !custom {
+		return fieldChange{}, false
+	}
+
+	oldV := fmt.Sprintf("%v", activity["old_value"])
+	newV := fmt.Sprintf("%v", activity["new_value"])
+	rawSame := oldV == newV
+
+	if field == "priority" {
+		oldV = priorityLabel(oldV)
+		newV = priorityLabel(newV)
+	}
+
+	resolved := ""
+	if field == "state_id" || field == "state" {
+		field = "State"
+		if st, ok := data["state"].(map[string]interface{}); ok {
+			newV, _ = st["name"].(string)
+			if group, _ := st["group"].(string); group == "completed" {
+				resolved = resolutionTime(data)
+			}
+		}
+		if isUnset(oldV) {
+			oldV = "None"
+		} else {
+			oldV = "Changed"
+		}
+	}
+
+	pastDue := false
+	if field == "target_date" {
+		field = "Target Date"
+		pastDue = isPastDue(newV)
+		oldV = formatDate(oldV)
+		newV = formatDate(newV)
+	}
+
+	if field == "module_id" || field == "module" {
+		field = "Module"
+		if isUnset(newV) {
+			newV = "None"
+		} else if md, ok := data["module_detail"].(map[string]interface{}); ok {
+			if n, ok := md["name"].(string); ok && n != "" {
+				newV = n
+			}
+		}
+		if isUnset(oldV) {
+			oldV = "None"
+		} else {
+			oldV = "Changed"
+		}
+	}
+
+	if field == "project_id" || field == "project" {
+		field = "Project"
+		if name, ok := projectName(oldV); ok {
+			oldV = name
+		} else {
+			oldV = "another project"
+		}
+		// The payload describes the issue in its new project
+		if name := projectNameOf(data); name != "" {
+			newV = name
+		} else if name, ok := projectName(newV); ok {
+			newV = name
+		}
+	}
+
+	if field == "is_draft" {
+		field = "Draft"
+		oldV = fmt.Sprintf("%t", strings.EqualFold(oldV, "true"))
+		newV = fmt.Sprintf("%t", strings.EqualFold(newV, "true"))
+	}
+
+	image, diff := "", ""
+	if field == "cover_image" {
+		field = "Cover Image"
+		if !isUnset(newV) {
+			image = newV
+			if image[0] == '/' {
+				image = AppURL + image
+			}
+		}
+		if isUnset(oldV) {
+			oldV = "None"
+		} else {
+			oldV = "Set"
+		}
+		newV = "Removed"
+		if image != "" {
+			newV = "Updated"
+		}
+	}
+
+	if field == "assignee_ids" {
+		field = "Assignees"
+		diff = assigneeDiff(data, activity["old_value"])
+		oldV, newV = assigneeChange(data, oldV)
+	}
+
+	if custom {
+		if isUnset(oldV) {
+			oldV = "None"
+		}
+		if isUnset(newV) {
+			newV = "None"
+		}
+	}
+
+	return fieldChange{label: field, oldV: oldV, newV: newV, custom: custom, pastDue: pastDue, resolved: resolved, image: image, diff: diff, noop: rawSame || oldV == newV, activity: activity}, true
+}
+
+// resolutionTime renders how long an issue took from created_at to
+// completed_at (or now), e.g. "3d 4h"; "" if created_at is missing.
+func resolutionTime(data map[string]interface{}) string {
+	created, _ := data["created_at"].(string)
+	start, err := time.Parse(time.RFC3339Nano, created)
+	if err != nil {
+		return ""
+	}
+	end := time.Now()
+	if completed, ok := data["completed_at"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, completed); err == nil {
+			end = t
+		}
+	}
+	d := end.Sub(start)
+	if d < 0 {
+		return ""
+	}
+	days, hours := int(d.Hours())/24, int(d.Hours())%24
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+// activityText returns Plane's description of an activity, or "" when absent.
+// Plane sends it as "comment"; "verb" alone ("updated") says too little to use.
+func activityText(activity map[string]interface{}) string {
+	text, _ := activity["comment"].(string)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	first, size := utf8.DecodeRuneInString(text)
+	return string(unicode.ToUpper(first)) + text[size:]
+}
+
+// changeField renders a change as an embed field for multi-field updates.
+func changeField(data map[string]interface{}, c fieldChange) EmbedField {
+	if verbs, ok := relationVerbs[c.label]; ok {
+		return EmbedField{Name: "Relation", Value: relationChange(data, c.activity, verbs, c.oldV, c.newV)}
+	}
+	if c.diff != "" {
+		return EmbedField{Name: c.label, Value: c.diff, change: true}
+	}
+	label := c.label
+	if label == "name" {
+		label = "Name"
+	}
+	return EmbedField{
+		Name:   label,
+		Value:  fmt.Sprintf("`%s` → `%s`", truncate(c.oldV, 480), truncate(c.newV, 480)),
+		change: true,
+	}
+}
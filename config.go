@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// --- Config File ---
+// CONFIG_FILE names a JSON object of settings keyed like the env vars, e.g.
+// {"DISCORD_WEBHOOK_URL": ["https://..."], "USER_MAP": {"uuid": "Alice"}}.
+// Lists are joined with commas and objects become "k=v" lists; env vars win.
+// YAML is not supported to keep the bridge free of dependencies.
+var (
+	fileConfig     map[string]string
+	fileConfigOnce sync.Once
+)
+
+// configFileValue returns a setting from CONFIG_FILE, loading it on first use
+// since settings are read while package variables initialize.
+func configFileValue(key string) (string, bool) {
+	fileConfigOnce.Do(loadConfigFile)
+	v, ok := fileConfig[key]
+	return v, ok
+}
+
+func loadConfigFile() {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("[FATAL] Reading CONFIG_FILE: %v", err)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		log.Fatalf("[FATAL] Parsing CONFIG_FILE: %v", err)
+	}
+	fileConfig = make(map[string]string, len(values))
+	for key, v := range values {
+		fileConfig[key] = configString(v)
+	}
+	log.Printf("[INFO] Loaded %d settings from %s", len(fileConfig), path)
+}
+
+// configString flattens a JSON value into the env var format.
+func configString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = configString(item)
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			// Multiple values per key use "|", as in PRIORITY_WEBHOOKS
+			if list, ok := val[k].([]interface{}); ok {
+				parts := make([]string, len(list))
+				for j, item := range list {
+					parts[j] = configString(item)
+				}
+				items[i] = k + "=" + strings.Join(parts, "|")
+				continue
+			}
+			items[i] = k + "=" + configString(val[k])
+		}
+		return strings.Join(items, ",")
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
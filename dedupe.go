@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Duplicate Suppression ---
+// Plane occasionally redelivers the same activity; identical embeds for the
+// same issue inside the window are dropped. 0 disables the check.
+var DuplicateWindow = getEnvDuration("DUPLICATE_WINDOW", 30*time.Second)
+
+// Scope of the update debounce: "issue" (any update to the issue), "field"
+// (repeat updates of the same fields) or "delivery" (Plane redelivering one
+// webhook, matched by X-Plane-Delivery for every event within DEDUP_DELIVERY_WINDOW)
+var (
+	DedupMode           = strings.ToLower(getEnv("DEDUP_MODE", "issue"))
+	DedupDeliveryWindow = getEnvDuration("DEDUP_DELIVERY_WINDOW", 10*time.Minute)
+)
+
+// Delivery IDs forwarded within DEDUP_DELIVERY_WINDOW, pruned as they expire
+var (
+	seenDeliveries   = make(map[string]time.Time)
+	seenDeliveriesMu sync.Mutex
+)
+
+func setupDedup() {
+	switch DedupMode {
+	case "issue", "field", "delivery":
+	default:
+		log.Fatalf("[FATAL] Invalid DEDUP_MODE %q: expected issue, field or delivery", DedupMode)
+	}
+}
+
+// dedupKey returns the debounce key of an update; "delivery" falls back to the issue.
+func dedupKey(issueID string, activities []map[string]interface{}) string {
+	if DedupMode == "field" {
+		var fields []string
+		for _, a := range activities {
+			fields = append(fields, fmt.Sprintf("%v", a["field"]))
+		}
+		sort.Strings(fields)
+		return issueID + ":" + strings.Join(fields, ",")
+	}
+	return issueID
+}
+
+// deliveryID returns the webhook's X-Plane-Delivery ID under DEDUP_MODE=delivery.
+func deliveryID(r *http.Request) string {
+	if DedupMode != "delivery" {
+		return ""
+	}
+	return r.Header.Get("X-Plane-Delivery")
+}
+
+// deliverySeen reports whether a webhook with this delivery ID was already
+// forwarded within DEDUP_DELIVERY_WINDOW, pruning expired IDs.
+func deliverySeen(id string, now time.Time) bool {
+	if id == "" {
+		return false
+	}
+	seenDeliveriesMu.Lock()
+	defer seenDeliveriesMu.Unlock()
+	for k, at := range seenDeliveries {
+		if now.Sub(at) >= DedupDeliveryWindow {
+			delete(seenDeliveries, k)
+		}
+	}
+	_, ok := seenDeliveries[id]
+	return ok
+}
+
+// recordDelivery remembers a delivery ID once its webhook was accepted, so
+// Plane retrying a rejected delivery is still forwarded.
+func recordDelivery(id string, now time.Time) {
+	if id == "" {
+		return
+	}
+	seenDeliveriesMu.Lock()
+	seenDeliveries[id] = now
+	seenDeliveriesMu.Unlock()
+}
+
+type sentEmbed struct {
+	sum [32]byte
+	at  time.Time
+}
+
+var (
+	lastSent   = make(map[string]sentEmbed)
+	lastSentMu sync.Mutex
+)
+
+// embedSum hashes an embed for duplicate detection.
+func embedSum(embed DiscordEmbed) [32]byte {
+	// The timestamp falls back to "now" when Plane omits it, so ignore it
+	embed.Timestamp = ""
+	raw, _ := json.Marshal(embed)
+	return sha256.Sum256(raw)
+}
+
+// isDuplicate reports whether embed matches the last one sent for key within the window.
+func isDuplicate(key string, embed DiscordEmbed) bool {
+	if DuplicateWindow <= 0 || key == "" {
+		return false
+	}
+	sum := embedSum(embed)
+	lastSentMu.Lock()
+	defer lastSentMu.Unlock()
+	prev, ok := lastSent[key]
+	return ok && prev.sum == sum && time.Since(prev.at) < DuplicateWindow
+}
+
+// recordSent remembers embed as sent for key. It is called only once the
+// event was accepted for delivery, so a rejected event's retry is not a duplicate.
+func recordSent(key string, embed DiscordEmbed) {
+	if DuplicateWindow <= 0 || key == "" {
+		return
+	}
+	sum := embedSum(embed)
+	lastSentMu.Lock()
+	defer lastSentMu.Unlock()
+	now := time.Now()
+	lastSent[key] = sentEmbed{sum: sum, at: now}
+
+	// Keep the map from growing without bound
+	for k, v := range lastSent {
+		if now.Sub(v.at) >= DuplicateWindow {
+			delete(lastSent, k)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliveryDedupAllEvents(t *testing.T) {
+	prev := DedupMode
+	DedupMode = "delivery"
+	t.Cleanup(func() { DedupMode = prev })
+	discord := newMockDiscord(t)
+
+	post := func(delivery string) *httptest.ResponseRecorder {
+		body := `{"event": "issue_comment", "action": "created", "data": {"id": "c-1", "issue": "issue-redelivered", "comment_stripped": "Hi ` + delivery + `"}}`
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("X-Plane-Delivery", delivery)
+		rec := httptest.NewRecorder()
+		webhookHandler(rec, req)
+		return rec
+	}
+	post("d-1")
+	if rec := post("d-1"); !strings.Contains(rec.Body.String(), "duplicate delivery") {
+		t.Errorf("redelivered comment: %s", rec.Body)
+	}
+	post("d-2")
+	if n := len(discord.embeds(t)); n != 2 {
+		t.Errorf("Discord received %d embeds, want 2", n)
+	}
+}
+
+func TestDeliverySeenExpires(t *testing.T) {
+	now := time.Now()
+	recordDelivery("d-expiring", now)
+	if !deliverySeen("d-expiring", now.Add(DedupDeliveryWindow-time.Second)) {
+		t.Error("delivery forgotten inside the window")
+	}
+	if deliverySeen("d-expiring", now.Add(DedupDeliveryWindow)) {
+		t.Error("delivery remembered past the window")
+	}
+	seenDeliveriesMu.Lock()
+	defer seenDeliveriesMu.Unlock()
+	if _, ok := seenDeliveries["d-expiring"]; ok {
+		t.Error("expired delivery not pruned")
+	}
+}
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Discord Delivery ---
+var (
+	DiscordThreadID = getEnv("DISCORD_THREAD_ID", "")  // post into an existing thread
+	MessageStyle    = getEnv("MESSAGE_STYLE", "embed") // "embed", "compact" or "plain"
+
+	// For forum channels: one thread per project, created on first use
+	ForumThreadsByProject = getEnvBool("FORUM_THREADS_BY_PROJECT", false)
+
+	// Link later updates back to the issue's creation message; DISCORD_GUILD_ID is needed to build the link
+	ReplyToCreated = getEnvBool("REPLY_TO_CREATED", false)
+	DiscordGuildID = getEnv("DISCORD_GUILD_ID", "")
+
+	// Ask Discord to return each created message so its ID can be kept per issue
+	DiscordWait = getEnvBool("DISCORD_WAIT", false)
+
+	// Edit the issue's last message for updates instead of posting a new one
+	EditInPlace = getEnvBool("EDIT_IN_PLACE", false)
+
+	// Longest 429 wait a delivery blocks for; longer waits are retried in the background
+	RateLimitMaxWait = getEnvDuration("RATE_LIMIT_MAX_WAIT", 5*time.Second)
+)
+
+// deliveryContext carries what delivery needs to know about an event beyond its embed.
+type deliveryContext struct {
+	project  string // project name, used as the forum thread name
+	issueKey string // issue the event belongs to
+	action   string
+	coalesce bool   // replaces the issue's creation message, see COALESCE_CREATE_WINDOW
+	mention  string // role ID pinged alongside the message
+
+	// The issue's current state, sent instead of the update's diff when
+	// EDIT_IN_PLACE replaces the stored message
+	current *DiscordEmbed
+}
+
+// discordMessage is the subset of the message Discord returns with ?wait=true.
+type discordMessage struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// Issue actions that edit the stored message under EDIT_IN_PLACE
+var editableActions = map[string]bool{
+	"updated":    true,
+	"assigned":   true,
+	"unassigned": true,
+}
+
+// Serializes forum thread creation per project so concurrent events don't open
+// duplicate threads; threadMu only guards the map of per-project locks
+var (
+	threadMu    sync.Mutex
+	threadLocks = map[string]*sync.Mutex{}
+)
+
+func threadLock(key string) *sync.Mutex {
+	threadMu.Lock()
+	defer threadMu.Unlock()
+	l, ok := threadLocks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		threadLocks[key] = l
+	}
+	return l
+}
+
+// Bounds simultaneous webhook POSTs during fan-out
+var deliverySlots = make(chan struct{}, max(MaxConcurrentDeliveries, 1))
+
+// A hung webhook must not hold its delivery slot forever
+var discordClient = &http.Client{Timeout: 10 * time.Second}
+
+// Rate limited requests are retried inline at most this many times, then in
+// the background at most maxBackgroundRetries times
+const (
+	maxRateLimitRetries  = 3
+	maxBackgroundRetries = 5
+)
+
+var metricRateLimitDropped = expvar.NewInt("discord_deliveries_dropped_rate_limited")
+
+// Discord rejects embeds with more than this many fields
+const maxEmbedFields = 25
+
+// Discord limits a message to 10 embeds totalling 6000 characters
+const (
+	maxBatchEmbeds = 10
+	maxBatchChars  = 6000
+)
+
+// Change lists (digests, bulk edits) collapse beyond this many fields. 0 shows all.
+var MaxChangeFields = getEnvInt("MAX_CHANGE_FIELDS", 10)
+
+// collapseFields keeps at most limit fields, replacing the overflow with a
+// summary field whose value is more formatted with the hidden count.
+func collapseFields(fields []EmbedField, limit int, more string) []EmbedField {
+	if limit <= 0 || len(fields) <= limit {
+		return fields
+	}
+	hidden := len(fields) - (limit - 1)
+	capped := append([]EmbedField{}, fields[:limit-1]...)
+	return append(capped, EmbedField{Name: "…", Value: fmt.Sprintf(more, hidden), change: fields[limit-1].change})
+}
+
+// Per-field inline overrides by field name, e.g. FIELD_INLINE="change=true,parent=false";
+// "*" applies to every field without its own entry
+var FieldInline = getEnvMap("FIELD_INLINE")
+
+// inlineFields applies FIELD_INLINE to a copy of fields.
+func inlineFields(fields []EmbedField) []EmbedField {
+	if len(FieldInline) == 0 {
+		return fields
+	}
+	out := make([]EmbedField, len(fields))
+	for i, f := range fields {
+		v, ok := FieldInline[strings.ToLower(f.Name)]
+		if !ok {
+			v, ok = FieldInline["*"]
+		}
+		if b, err := strconv.ParseBool(v); ok && err == nil {
+			f.Inline = b
+		}
+		out[i] = f
+	}
+	return out
+}
+
+// capFields trims fields to Discord's limit.
+func capFields(fields []EmbedField) []EmbedField {
+	return collapseFields(fields, maxEmbedFields, "…and %d more")
+}
+
+// botUsername tags the webhook username with ENVIRONMENT, e.g. "Plane [staging]".
+func botUsername() string {
+	if Environment != "" {
+		return fmt.Sprintf("Plane [%s]", Environment)
+	}
+	return "Plane"
+}
+
+// sendToDiscord fans the embed out to urls. With DISCORD_WAIT the returned
+// slice holds the message created on each target (nil where none was returned).
+func sendToDiscord(urls []string, embed DiscordEmbed, ctx deliveryContext) []*discordMessage {
+	return sendEmbeds(urls, []DiscordEmbed{embed}, ctx)
+}
+
+// sendEmbeds posts up to maxBatchEmbeds embeds to each target as one message.
+func sendEmbeds(urls []string, embeds []DiscordEmbed, ctx deliveryContext) []*discordMessage {
+	payload := buildPayload(embeds, ctx.mention)
+	var edit map[string]interface{}
+	if ctx.current != nil {
+		edit = buildPayload([]DiscordEmbed{*ctx.current}, "")
+	}
+
+	msgs := make([]*discordMessage, len(urls))
+	var wg sync.WaitGroup
+	for i, target := range urls {
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			deliverySlots <- struct{}{}
+			defer func() { <-deliverySlots }()
+			if slackURL, ok := slackTarget(target); ok {
+				sendSlack(slackURL, embeds)
+				return
+			}
+			msgs[i] = deliver(target, payload, edit, ctx)
+		}(i, target)
+	}
+	wg.Wait()
+	return msgs
+}
+
+// buildPayload renders embeds in MESSAGE_STYLE, pinging the mention role if set.
+func buildPayload(embeds []DiscordEmbed, mention string) map[string]interface{} {
+	out := make([]DiscordEmbed, len(embeds))
+	var lines []string
+	for i, embed := range embeds {
+		embed.Fields = inlineFields(capFields(embed.Fields))
+		if embed.Color == 0 {
+			embed.Color = DefaultColor
+		}
+		if !ShowFooter {
+			embed.Footer = nil
+		}
+		if MessageStyle == "compact" {
+			embed = compactEmbed(embed)
+		}
+		out[i] = embed
+		lines = append(lines, plainContent(embed))
+	}
+	payload := map[string]interface{}{
+		"username":   botUsername(),
+		"avatar_url": fmt.Sprintf("%s/img/plane-icon.png", AppURL),
+	}
+	if MessageStyle == "plain" {
+		payload["content"] = truncate(strings.Join(lines, "\n"), 2000)
+	} else {
+		payload["embeds"] = out
+	}
+	if mention != "" {
+		ping := fmt.Sprintf("<@&%s>", mention)
+		if content, _ := payload["content"].(string); content != "" {
+			ping = truncate(ping+" "+content, 2000)
+		}
+		payload["content"] = ping
+		payload["allowed_mentions"] = map[string]interface{}{"roles": []string{mention}}
+	}
+	return payload
+}
+
+// deliver posts a payload to one webhook, routing it into the project's forum
+// thread and linking it to the issue's creation message when enabled. An edit
+// of the stored message sends edit instead when it is set.
+func deliver(target string, payload, edit map[string]interface{}, ctx deliveryContext) *discordMessage {
+	params := url.Values{}
+	if DiscordThreadID != "" {
+		params.Set("thread_id", DiscordThreadID)
+	}
+
+	if ctx.issueKey != "" && (ctx.coalesce || EditInPlace && editableActions[ctx.action]) {
+		if ref := stateGet("messages", targetKey(target, ctx.issueKey)); ref != "" {
+			body := payload
+			if edit != nil {
+				body = edit
+			}
+			if msg := editMessage(target, ref, body); msg != nil {
+				return msg
+			}
+			log.Printf("[WARN] Editing message for issue %s failed, posting a new one", ctx.issueKey)
+		}
+	}
+
+	trackCreated := ReplyToCreated && ctx.action == "created" && ctx.issueKey != ""
+	keepMessage := DiscordWait || EditInPlace || CoalesceCreateWindow > 0 && ctx.action == "created"
+	if trackCreated || keepMessage {
+		params.Set("wait", "true")
+	}
+	if !trackCreated && ReplyToCreated && ctx.issueKey != "" {
+		payload = withOriginLink(target, payload, ctx.issueKey)
+	}
+
+	var msg *discordMessage
+	if ForumThreadsByProject {
+		msg = postToForum(target, params, payload, ctx)
+	} else {
+		body, _ := json.Marshal(payload)
+		msg = postWebhook(target, params, body)
+	}
+
+	if msg != nil {
+		if link := messageLink(msg.ChannelID + "/" + msg.ID); link != "" {
+			log.Printf("[INFO] Delivered %s for issue %s as message %s: %s", ctx.action, ctx.issueKey, msg.ID, link)
+		} else {
+			log.Printf("[INFO] Delivered %s for issue %s as message %s in channel %s", ctx.action, ctx.issueKey, msg.ID, msg.ChannelID)
+		}
+	}
+
+	if msg != nil && ctx.issueKey != "" {
+		ref := msg.ChannelID + "/" + msg.ID
+		if trackCreated {
+			stateSet("created", targetKey(target, ctx.issueKey), ref)
+		}
+		if keepMessage {
+			stateSet("messages", targetKey(target, ctx.issueKey), ref)
+		}
+	}
+	return msg
+}
+
+// postToThread posts into the project's existing forum thread, reporting
+// false when none has been opened yet.
+func postToThread(target, key string, params url.Values, payload map[string]interface{}) (*discordMessage, bool) {
+	id := stateGet("threads", key)
+	if id == "" {
+		return nil, false
+	}
+	params.Set("thread_id", id)
+	body, _ := json.Marshal(payload)
+	return postWebhook(target, params, body), true
+}
+
+// postToForum posts into the project's forum thread, opening it on first use.
+func postToForum(target string, params url.Values, payload map[string]interface{}, ctx deliveryContext) *discordMessage {
+	name := ctx.project
+	if name == "" {
+		name = WorkspaceName
+	}
+	key := targetKey(target, strings.ToLower(name))
+
+	if msg, ok := postToThread(target, key, params, payload); ok {
+		return msg
+	}
+
+	// Another event for the project may have opened the thread while we waited
+	lock := threadLock(key)
+	lock.Lock()
+	defer lock.Unlock()
+	if msg, ok := postToThread(target, key, params, payload); ok {
+		return msg
+	}
+
+	// First message for this project opens the thread; its channel ID is the thread ID
+	withThread := map[string]interface{}{"thread_name": truncate(name, 100)}
+	for k, v := range payload {
+		withThread[k] = v
+	}
+	params.Set("wait", "true")
+	body, _ := json.Marshal(withThread)
+	msg := postWebhook(target, params, body)
+	if msg != nil && msg.ChannelID != "" {
+		stateSet("threads", key, msg.ChannelID)
+		log.Printf("[INFO] Created forum thread %q (%s)", name, msg.ChannelID)
+	}
+	return msg
+}
+
+// withOriginLink prefixes the message with a jump link to the issue's
+// creation message. Webhooks cannot send true replies, but Discord renders
+// message links as a reference to the original.
+func withOriginLink(target string, payload map[string]interface{}, issueKey string) map[string]interface{} {
+	origin := messageLink(stateGet("created", targetKey(target, issueKey)))
+	if origin == "" {
+		return payload
+	}
+	link := "↪ " + origin
+	out := make(map[string]interface{}, len(payload)+1)
+	for k, v := range payload {
+		out[k] = v
+	}
+	if content, ok := out["content"].(string); ok && content != "" {
+		link = truncate(link+"\n"+content, 2000)
+	}
+	out["content"] = link
+	return out
+}
+
+// messageLink builds a jump link for a stored "channel/message" reference, or
+// "" when DISCORD_GUILD_ID is not set.
+func messageLink(ref string) string {
+	if ref == "" || DiscordGuildID == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://discord.com/channels/%s/%s", DiscordGuildID, ref)
+}
+
+// targetKey namespaces a key per webhook without persisting the webhook token.
+func targetKey(target, key string) string {
+	sum := sha256.Sum256([]byte(target))
+	return hex.EncodeToString(sum[:8]) + ":" + key
+}
+
+// embedChars approximates the characters Discord counts toward the message total.
+func embedChars(e DiscordEmbed) int {
+	n := len([]rune(e.Title)) + len([]rune(e.Description))
+	if e.Author != nil {
+		n += len([]rune(e.Author.Name))
+	}
+	if e.Footer != nil {
+		n += len([]rune(e.Footer.Text))
+	}
+	for _, f := range e.Fields {
+		n += len([]rune(f.Name)) + len([]rune(f.Value))
+	}
+	return n
+}
+
+// compactEmbed folds the author into the title and keeps only the change
+// fields and the first line of the description, or all of it when it lists changes.
+func compactEmbed(embed DiscordEmbed) DiscordEmbed {
+	out := DiscordEmbed{
+		Title:     embed.Title,
+		URL:       embed.URL,
+		Color:     embed.Color,
+		Timestamp: embed.Timestamp,
+	}
+	if embed.Author != nil && embed.Author.Name != "" {
+		out.Title = strings.TrimSuffix(embed.Author.Name+" · "+embed.Title, " · ")
+	}
+	out.Title = truncate(out.Title, 256)
+	line, _, _ := strings.Cut(embed.Description, "\n")
+	out.Description = truncate(line, 200)
+	if embed.changeLines {
+		out.Description = truncate(embed.Description, 1024)
+	}
+	for _, f := range embed.Fields {
+		if isChangeField(f) {
+			out.Fields = append(out.Fields, f)
+		}
+	}
+	return out
+}
+
+// isChangeField reports whether a field shows a change: the single "Change"
+// field, or one tagged as a change such as a merged update's or a rename's.
+func isChangeField(f EmbedField) bool {
+	return f.Name == "Change" || f.change
+}
+
+// plainContent condenses an embed into a single line for MESSAGE_STYLE=plain.
+func plainContent(embed DiscordEmbed) string {
+	var parts []string
+	if embed.Author != nil && embed.Author.Name != "" {
+		parts = append(parts, "**"+embed.Author.Name+"**")
+	}
+	summary := embed.Title
+	if summary == "" && !embed.changeLines {
+		summary, _, _ = strings.Cut(embed.Description, "\n")
+	}
+	if summary != "" {
+		parts = append(parts, summary)
+	}
+	if embed.changeLines {
+		parts = append(parts, strings.Split(embed.Description, "\n")...)
+	}
+	for _, f := range embed.Fields {
+		if !isChangeField(f) {
+			continue
+		}
+		value := strings.ReplaceAll(f.Value, "\n", " · ")
+		if f.Name != "Change" {
+			value = f.Name + ": " + value
+		}
+		parts = append(parts, value)
+	}
+	line := strings.Join(parts, " · ")
+	if embed.URL != "" {
+		// Angle brackets stop Discord from unfurling the link
+		line += " <" + embed.URL + ">"
+	}
+	return truncate(line, 2000)
+}
+
+// webhookURL adds query parameters such as thread_id to a webhook URL.
+func webhookURL(raw string, params url.Values) string {
+	u, err := url.Parse(raw)
+	if err != nil || len(params) == 0 {
+		return raw
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// editMessage replaces a stored "channel/message" with payload via the webhook PATCH endpoint.
+func editMessage(target, ref string, payload map[string]interface{}) *discordMessage {
+	channelID, messageID, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/messages/" + messageID
+
+	params := url.Values{}
+	if ForumThreadsByProject || DiscordThreadID != "" {
+		// Messages inside threads can only be edited with the thread's ID
+		params.Set("thread_id", channelID)
+	}
+	// Username and avatar are fixed at creation and rejected on edit
+	edit := map[string]interface{}{"embeds": payload["embeds"], "content": payload["content"]}
+	body, _ := json.Marshal(edit)
+	return callWebhook(http.MethodPatch, u.String(), params, body)
+}
+
+// postWebhook executes a webhook, returning the created message when params ask Discord to wait.
+func postWebhook(target string, params url.Values, body []byte) *discordMessage {
+	return callWebhook(http.MethodPost, target, params, body)
+}
+
+func callWebhook(method, target string, params url.Values, body []byte) *discordMessage {
+	for attempt := 1; ; attempt++ {
+		msg, retryAfter := callWebhookOnce(method, target, params, body)
+		if retryAfter == 0 {
+			return msg
+		}
+		if retryAfter > RateLimitMaxWait || attempt >= maxRateLimitRetries {
+			// Retry in the background rather than stall the worker for a long wait
+			log.Printf("[WARN] Discord rate limited for %s, retrying in the background", retryAfter)
+			retryLater(method, target, params, body, retryAfter, 1)
+			return nil
+		}
+		log.Printf("[INFO] Discord rate limited, retrying in %s", retryAfter)
+		time.Sleep(retryAfter)
+	}
+}
+
+// Webhooks Discord reported as deleted or unauthorized are skipped until restart
+var disabledWebhooks sync.Map // target URL -> status that disabled it
+
+func init() {
+	expvar.Publish("discord_webhooks_disabled", expvar.Func(func() interface{} {
+		return disabledWebhookCount()
+	}))
+}
+
+func disabledWebhookCount() int {
+	n := 0
+	disabledWebhooks.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// webhookGone reports whether Discord rejected the webhook itself rather than
+// a message or thread: 401 for an invalid token, or 404 "Unknown Webhook".
+func webhookGone(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return true
+	case http.StatusNotFound:
+		var apiErr struct {
+			Code int `json:"code"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return apiErr.Code == 10015
+	}
+	return false
+}
+
+// redactWebhook drops the token from a webhook URL so it can be logged.
+func redactWebhook(target string) string {
+	if i := strings.LastIndex(strings.TrimRight(target, "/"), "/"); i > 0 {
+		return target[:i] + "/…"
+	}
+	return "…"
+}
+
+// retryLater repeats a rate limited request after wait, taking a delivery slot
+// like any other POST and backing off again on another 429. After
+// maxBackgroundRetries the request is dropped with an error log.
+func retryLater(method, target string, params url.Values, body []byte, wait time.Duration, tries int) {
+	time.AfterFunc(wait, func() {
+		deliverySlots <- struct{}{}
+		defer func() { <-deliverySlots }()
+		_, retryAfter := callWebhookOnce(method, target, params, body)
+		if retryAfter == 0 {
+			return
+		}
+		if tries >= maxBackgroundRetries {
+			metricRateLimitDropped.Add(1)
+			log.Printf("[ERROR] Discord still rate limiting %s after %d background retries, dropping message: %s",
+				redactWebhook(target), tries, truncate(string(body), 500))
+			return
+		}
+		log.Printf("[WARN] Discord rate limited again for %s, retrying in the background", retryAfter)
+		retryLater(method, target, params, body, retryAfter, tries+1)
+	})
+}
+
+// callWebhookOnce performs one request. A 429 returns how long Discord asks to wait.
+func callWebhookOnce(method, target string, params url.Values, body []byte) (*discordMessage, time.Duration) {
+	if _, disabled := disabledWebhooks.Load(target); disabled {
+		return nil, 0
+	}
+	breaker := breakerFor(target)
+	if !breaker.allow() {
+		log.Printf("[WARN] Discord circuit open for %s, dropping delivery", redactWebhook(target))
+		return nil, 0
+	}
+	req, err := http.NewRequest(method, webhookURL(target, params), bytes.NewBuffer(body))
+	if err != nil {
+		log.Printf("Error sending to Discord: %v", err)
+		return nil, 0
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := discordClient.Do(req)
+	if err != nil {
+		log.Printf("Error sending to Discord: %v", err)
+		breaker.record(false)
+		metricFailed.Add(1)
+		return nil, 0
+	}
+	defer resp.Body.Close()
+
+	// Only server-side errors indicate an outage
+	breaker.record(resp.StatusCode < 500)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, retryAfterOf(resp)
+	}
+	// Only a 2xx is a delivery; rate limits are retried and counted then
+	if resp.StatusCode >= 300 {
+		metricFailed.Add(1)
+	} else {
+		metricDelivered.Add(1)
+	}
+	if webhookGone(resp) {
+		log.Printf("[WARN] Discord webhook %s returned %s: it was deleted or its token is invalid; disabling it until restart",
+			redactWebhook(target), resp.Status)
+		disabledWebhooks.Store(target, resp.Status)
+		return nil, 0
+	}
+	if resp.StatusCode >= 300 {
+		log.Printf("[WARN] Discord returned %s", resp.Status)
+		return nil, 0
+	}
+	// PATCH always returns the message; POST only when asked to wait
+	if method == http.MethodPost && params.Get("wait") != "true" {
+		return nil, 0
+	}
+	var msg discordMessage
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		log.Printf("[WARN] Decoding Discord message: %v", err)
+		return nil, 0
+	}
+	return &msg, 0
+}
+
+// retryAfterOf reads the rate limit wait from a 429, preferring the JSON
+// retry_after (seconds, fractional) over the Retry-After header.
+func retryAfterOf(resp *http.Response) time.Duration {
+	var rl struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&rl) == nil && rl.RetryAfter > 0 {
+		return time.Duration(rl.RetryAfter * float64(time.Second))
+	}
+	if secs, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil && secs > 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return time.Second
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedRetriedInBackground(t *testing.T) {
+	prev := RateLimitMaxWait
+	RateLimitMaxWait = 0
+	t.Cleanup(func() { RateLimitMaxWait = prev })
+
+	var calls atomic.Int32
+	delivered := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Rate limit the inline attempt and the first background retry
+		if calls.Add(1) <= 2 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"retry_after": 0.01}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		close(delivered)
+	}))
+	defer srv.Close()
+
+	postWebhook(srv.URL, nil, []byte(`{}`))
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("message not delivered after %d attempts", calls.Load())
+	}
+}
+
+func TestPostToForumOpensOneThread(t *testing.T) {
+	var threads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&p)
+		if _, ok := p["thread_name"]; ok {
+			threads.Add(1)
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "1", "channel_id": "thread-1"}`))
+	}))
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			postToForum(srv.URL, url.Values{}, map[string]interface{}{"content": "hi"}, deliveryContext{project: "Forum Test"})
+		}()
+	}
+	wg.Wait()
+	if n := threads.Load(); n != 1 {
+		t.Errorf("opened %d threads, want 1", n)
+	}
+}
+
+func TestCallWebhookTimesOut(t *testing.T) {
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer srv.Close()
+	defer close(hang)
+	prev := discordClient.Timeout
+	discordClient.Timeout = 50 * time.Millisecond
+	t.Cleanup(func() { discordClient.Timeout = prev })
+
+	done := make(chan struct{})
+	go func() {
+		postWebhook(srv.URL, nil, []byte(`{}`))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook call still blocked on an endpoint that never responds")
+	}
+}
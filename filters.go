@@ -0,0 +1,139 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// --- Filters ---
+var (
+	RequiredLabels     = getEnvList("REQUIRED_LABELS")
+	RequiredLabelsMode = getEnv("REQUIRED_LABELS_MODE", "any") // "any" or "all"
+
+	// Bots such as the GitHub sync whose comments should not be forwarded
+	IgnoredCommentActors = getEnvList("IGNORED_COMMENT_ACTORS")
+
+	// Plane user ID the bridge's own integrations act as; its events are never forwarded
+	BridgeActorID = getEnv("BRIDGE_ACTOR_ID", "")
+
+	// Issues whose names match are not forwarded, e.g. NAME_EXCLUDE_PREFIXES="[TEST],WIP:"
+	NameExcludePrefixes = getEnvList("NAME_EXCLUDE_PREFIXES")
+	NameExcludePattern  = getEnv("NAME_EXCLUDE_REGEX", "")
+
+	// State names or IDs for which issue creation is announced, e.g. "Todo,In Progress"
+	NotifyCreatedStates = getEnvList("NOTIFY_CREATED_STATES")
+)
+
+// Compiled from NAME_EXCLUDE_REGEX by setupFilters
+var nameExcludeRegex *regexp.Regexp
+
+func setupFilters() {
+	if NameExcludePattern == "" {
+		return
+	}
+	re, err := regexp.Compile(NameExcludePattern)
+	if err != nil {
+		log.Fatalf("[FATAL] Invalid NAME_EXCLUDE_REGEX %q: %v", NameExcludePattern, err)
+	}
+	nameExcludeRegex = re
+}
+
+// issueLabels collects label names and IDs from an issue payload, lowercased.
+// Plane sends either label objects or bare label IDs depending on version.
+func issueLabels(data map[string]interface{}) map[string]bool {
+	labels := make(map[string]bool)
+	raw, _ := data["labels"].([]interface{})
+	if ids, ok := data["label_ids"].([]interface{}); ok {
+		raw = append(raw, ids...)
+	}
+	for _, l := range raw {
+		switch v := l.(type) {
+		case string:
+			labels[strings.ToLower(v)] = true
+		case map[string]interface{}:
+			if name, ok := v["name"].(string); ok {
+				labels[strings.ToLower(name)] = true
+			}
+			if id, ok := v["id"].(string); ok {
+				labels[strings.ToLower(id)] = true
+			}
+		}
+	}
+	return labels
+}
+
+// hasRequiredLabels reports whether an issue passes the REQUIRED_LABELS filter.
+func hasRequiredLabels(data map[string]interface{}) bool {
+	if len(RequiredLabels) == 0 {
+		return true
+	}
+	labels := issueLabels(data)
+	matched := 0
+	for _, want := range RequiredLabels {
+		if labels[strings.ToLower(want)] {
+			matched++
+		}
+	}
+	if RequiredLabelsMode == "all" {
+		return matched == len(RequiredLabels)
+	}
+	return matched > 0
+}
+
+// isExcludedName reports whether an issue name matches NAME_EXCLUDE_PREFIXES or NAME_EXCLUDE_REGEX.
+func isExcludedName(name string) bool {
+	for _, prefix := range NameExcludePrefixes {
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return nameExcludeRegex != nil && nameExcludeRegex.MatchString(name)
+}
+
+// notifiesCreatedState reports whether a new issue's state passes NOTIFY_CREATED_STATES.
+func notifiesCreatedState(data map[string]interface{}) bool {
+	if len(NotifyCreatedStates) == 0 {
+		return true
+	}
+	var keys []string
+	for _, k := range []string{"state", "state_detail"} {
+		switch st := data[k].(type) {
+		case string:
+			keys = append(keys, st)
+		case map[string]interface{}:
+			for _, f := range []string{"name", "id"} {
+				if v, ok := st[f].(string); ok {
+					keys = append(keys, v)
+				}
+			}
+		}
+	}
+	if id, ok := data["state_id"].(string); ok {
+		keys = append(keys, id)
+	}
+	for _, k := range keys {
+		for _, want := range NotifyCreatedStates {
+			if strings.EqualFold(k, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// actorMatches reports whether the actor's ID, display name or email is in the list.
+func actorMatches(actor map[string]interface{}, list []string) bool {
+	for _, key := range []string{"id", "display_name", "email"} {
+		v, _ := actor[key].(string)
+		if v == "" {
+			continue
+		}
+		for _, item := range list {
+			if strings.EqualFold(v, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
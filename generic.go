@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// --- Generic Delivery ---
+// With TARGET_PLATFORM=generic the bridge posts a normalized JSON event to
+// GENERIC_WEBHOOK_URL instead of Discord embeds.
+var (
+	TargetPlatform = getEnv("TARGET_PLATFORM", "discord") // "discord" or "generic"
+	GenericURLs    = getEnvList("GENERIC_WEBHOOK_URL")
+)
+
+var genericClient = &http.Client{Timeout: 10 * time.Second}
+
+type genericActor struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type genericIssue struct {
+	ID         string `json:"id,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Project    string `json:"project,omitempty"`
+	URL        string `json:"url,omitempty"`
+}
+
+type genericChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// genericEvent is the normalized payload posted to generic endpoints.
+type genericEvent struct {
+	Event     string          `json:"event"`
+	Action    string          `json:"action"`
+	Timestamp string          `json:"timestamp"`
+	Actor     genericActor    `json:"actor"`
+	Issue     *genericIssue   `json:"issue,omitempty"`
+	Changes   []genericChange `json:"changes,omitempty"`
+	Title     string          `json:"title,omitempty"`
+	Summary   string          `json:"summary,omitempty"`
+}
+
+func setupPlatform() {
+	switch TargetPlatform {
+	case "discord":
+	case "generic":
+		if len(GenericURLs) == 0 {
+			log.Fatalf("[FATAL] TARGET_PLATFORM=generic requires GENERIC_WEBHOOK_URL")
+		}
+	default:
+		log.Fatalf("[FATAL] Invalid TARGET_PLATFORM %q: expected discord or generic", TargetPlatform)
+	}
+}
+
+// normalizeEvent builds the generic payload from a webhook and its rendered embed.
+func normalizeEvent(event, action string, data map[string]interface{}, activities []map[string]interface{}, actor genericActor, embed DiscordEmbed) genericEvent {
+	ev := genericEvent{
+		Event:     event,
+		Action:    action,
+		Timestamp: embed.Timestamp,
+		Actor:     actor,
+		Title:     embed.Title,
+		Summary:   embed.Description,
+	}
+
+	issue := data
+	issueID := fmt.Sprintf("%v", data["id"])
+	if event != "issue" {
+		issue, _ = data["issue_detail"].(map[string]interface{})
+		issueID = fmt.Sprintf("%v", data["issue"])
+	}
+	if event == "issue" || issue != nil {
+		name, _ := issue["name"].(string)
+		ev.Issue = &genericIssue{
+			ID:         issueID,
+			Identifier: issueIdentifier(issue),
+			Name:       name,
+			Project:    projectNameOf(data),
+			URL:        embed.URL,
+		}
+	}
+
+	if event == "issue" && action == "updated" {
+		for _, a := range activities {
+			if c, ok := describeChange(data, a); ok {
+				ev.Changes = append(ev.Changes, genericChange{Field: c.label, Old: c.oldV, New: c.newV})
+			}
+		}
+	}
+	return ev
+}
+
+// sendGeneric posts a normalized event to each target, going through the same
+// circuit breakers and delivery slots as Discord webhooks. It reports whether
+// at least one endpoint accepted the event.
+func sendGeneric(targets []string, ev genericEvent) bool {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("[WARN] Encoding generic event: %v", err)
+		return false
+	}
+	delivered := false
+	for _, target := range targets {
+		deliverySlots <- struct{}{}
+		if postTracked(genericClient, "generic endpoint", target, body, nil) {
+			delivered = true
+		}
+		<-deliverySlots
+	}
+	return delivered
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGenericDelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantCode int
+	}{
+		{"accepted", http.StatusNoContent, http.StatusOK},
+		{"every endpoint failed", http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got genericEvent
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			prevPlatform, prevURLs := TargetPlatform, GenericURLs
+			TargetPlatform, GenericURLs = "generic", []string{srv.URL}
+			t.Cleanup(func() { TargetPlatform, GenericURLs = prevPlatform, prevURLs })
+
+			rec := postEvent(t, map[string]interface{}{
+				"event": "issue", "action": "created",
+				"data":     map[string]interface{}{"id": "issue-generic-" + tt.name, "name": "Login fails"},
+				"activity": map[string]interface{}{"actor": map[string]interface{}{"id": "u-alice", "display_name": "Alice"}},
+			})
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if got.Event != "issue" || got.Action != "created" || got.Issue == nil || got.Issue.Name != "Login fails" {
+				t.Errorf("generic event = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// --- Plane Links ---
+var PlaneEdition = strings.ToLower(strings.TrimSpace(getEnv("PLANE_EDITION", "cloud"))) // "cloud" or "self-hosted"
+
+type linkTemplates struct {
+	issue   string
+	project string
+	cycle   string
+}
+
+// Plane Cloud resolves work items by identifier, self-hosted instances by project and issue ID.
+var editionLinks = map[string]linkTemplates{
+	"cloud": {
+		issue:   "{app}/{workspace}/browse/{identifier}/",
+		project: "{app}/{workspace}/projects/{project}/issues/",
+		cycle:   "{app}/{workspace}/projects/{project}/cycles/{cycle}/",
+	},
+	"self-hosted": {
+		issue:   "{app}/{workspace}/projects/{project}/issues/{issue}/",
+		project: "{app}/{workspace}/projects/{project}/issues/",
+		cycle:   "{app}/{workspace}/projects/{project}/cycles/{cycle}/",
+	},
+}
+
+// setupLinks validates PLANE_EDITION at startup so links() only reads it.
+func setupLinks() {
+	if _, ok := editionLinks[PlaneEdition]; !ok {
+		log.Fatalf("[FATAL] Invalid PLANE_EDITION %q: expected cloud or self-hosted", PlaneEdition)
+	}
+}
+
+func links() linkTemplates {
+	return editionLinks[PlaneEdition]
+}
+
+// expandLink fills a link template, returning "" if any placeholder has no value.
+func expandLink(tmpl string, values map[string]string) string {
+	values["app"] = strings.TrimRight(AppURL, "/")
+	values["workspace"] = WorkspaceSlug
+	for k, v := range values {
+		if strings.Contains(tmpl, "{"+k+"}") && isUnset(v) {
+			return ""
+		}
+		tmpl = strings.ReplaceAll(tmpl, "{"+k+"}", v)
+	}
+	return tmpl
+}
+
+// projectIDOf returns the project ID from an issue or comment payload.
+func projectIDOf(data map[string]interface{}) string {
+	if id, ok := data["project_id"].(string); ok && id != "" {
+		return id
+	}
+	return fmt.Sprintf("%v", data["project"])
+}
+
+func issueURL(data map[string]interface{}, issueID string) string {
+	t := links()
+	if PlaneEdition == "cloud" {
+		if ident := issueIdentifier(data); ident != "" {
+			return expandLink(t.issue, map[string]string{"identifier": ident})
+		}
+		return projectURL(projectIDOf(data))
+	}
+	return expandLink(t.issue, map[string]string{"project": projectIDOf(data), "issue": issueID})
+}
+
+func projectURL(projectID string) string {
+	return expandLink(links().project, map[string]string{"project": projectID})
+}
+
+func cycleURL(projectID, cycleID string) string {
+	return expandLink(links().cycle, map[string]string{"project": projectID, "cycle": cycleID})
+}
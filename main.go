@@ -1,17 +1,27 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"crypto/hmac"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"expvar"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -20,9 +30,83 @@ var (
 	WorkspaceName = getEnv("WORKSPACE_NAME", "Workspace")
 	WorkspaceSlug = getEnv("WORKSPACE_SLUG", "workspace")
 	WebhookSecret = getEnv("WEBHOOK_SECRET", "")
-	DiscordURL    = getEnv("DISCORD_WEBHOOK_URL", "")
+	DiscordURLs   = getEnvList("DISCORD_WEBHOOK_URL") // comma-separated for fan-out
 	AppURL        = getEnv("APP_URL", "https://plane.so")
 	WebPort       = getEnv("WEB_PORT", "8080")
+
+	// HMAC hash used for webhook signatures: "sha256" or "sha1"
+	SignatureAlgo = strings.ToLower(getEnv("SIGNATURE_ALGO", "sha256"))
+
+	// Refuse to start without WEBHOOK_SECRET rather than accept unsigned webhooks
+	RequireSignature = getEnvBool("REQUIRE_SIGNATURE", false)
+
+	// Logs every signature check; requests with an X-Bridge-Test header get the result back
+	SignatureDiagnostics = getEnvBool("SIGNATURE_DIAGNOSTICS", false)
+
+	// Response code for events the bridge acknowledges but does not forward, e.g. 204 or 422
+	SkippedStatusCode = getEnvInt("SKIPPED_STATUS_CODE", http.StatusOK)
+
+	StartupNotification  = getEnvBool("STARTUP_NOTIFICATION", false)
+	ShutdownNotification = getEnvBool("SHUTDOWN_NOTIFICATION", false)
+
+	ReadTimeout  = getEnvDuration("READ_TIMEOUT", 10*time.Second)
+	WriteTimeout = getEnvDuration("WRITE_TIMEOUT", 30*time.Second) // covers synchronous Discord delivery
+	IdleTimeout  = getEnvDuration("IDLE_TIMEOUT", 60*time.Second)
+
+	// Used for actors without an avatar; AVATAR_IDENTICONS generates one per name instead
+	DefaultActorAvatarURL = getEnv("DEFAULT_ACTOR_AVATAR_URL", "")
+	AvatarIdenticons      = getEnvBool("AVATAR_IDENTICONS", false)
+
+	// HEAD-check avatars hosted outside APP_URL (e.g. on an SSO provider) and
+	// use the fallback avatar when they are unreachable
+	CheckAvatarURLs = getEnvBool("CHECK_AVATAR_URLS", false)
+
+	// Events from bot accounts and SYSTEM_ACTORS are labelled as automation
+	SystemActorLabel   = getEnv("SYSTEM_ACTOR_LABEL", "Plane Automation")
+	SystemActorIconURL = getEnv("SYSTEM_ACTOR_ICON_URL", "https://ui-avatars.com/api/?name=Auto&background=95a5a6&color=fff")
+	SystemActors       = getEnvList("SYSTEM_ACTORS")
+
+	// Right-side image: "assignee", "actor", "project-icon" or "none"
+	ThumbnailSource = strings.ToLower(getEnv("THUMBNAIL_SOURCE", "assignee"))
+
+	// Shown in the footer so bridges for different environments can be told apart, e.g. "staging"
+	Environment = getEnv("ENVIRONMENT", "")
+	ShowFooter  = getEnvBool("SHOW_FOOTER", true)
+
+	// How priorities render: "both" ("🔴 Urgent!"), "emoji" or "text"
+	PriorityStyle = strings.ToLower(getEnv("PRIORITY_STYLE", "both"))
+
+	TitleTemplate = getEnv("TITLE_TEMPLATE", "{name}") // placeholders: {identifier}, {name}, {project}
+
+	// Activity field names of custom issue properties to forward
+	CustomFields = getEnvList("CUSTOM_FIELDS")
+
+	// Subscribe/unsubscribe events are noisy, so they are off by default
+	NotifySubscriptions = getEnvBool("NOTIFY_SUBSCRIPTIONS", false)
+
+	// Announce saved views being created or updated
+	NotifyViews = getEnvBool("NOTIFY_VIEWS", false)
+
+	// Adds the issue's current state as a Status field on created and updated embeds
+	ShowStatusField = getEnvBool("SHOW_STATUS_FIELD", false)
+
+	// Adds the issue's current description to update embeds for context
+	IncludeDescriptionOnUpdate = getEnvBool("INCLUDE_DESCRIPTION_ON_UPDATE", false)
+
+	// Replaces the Priority and Assignees fields of created embeds with one
+	// Summary field listing state, priority, assignees and labels
+	CreatedSummary = getEnvBool("CREATED_SUMMARY", false)
+
+	CreatedShowAssignees    = getEnvBool("CREATED_SHOW_ASSIGNEES", false)
+	MaxAssigneesShown       = getEnvInt("MAX_ASSIGNEES_SHOWN", 10)
+	MaxConcurrentDeliveries = getEnvInt("MAX_CONCURRENT_DELIVERIES", 4)
+
+	// Webhooks processed at once; beyond this Plane gets a 503 and retries. 0 disables the limit
+	MaxInflightHandlers = getEnvInt("MAX_INFLIGHT_HANDLERS", 64)
+
+	// Embed descriptions are capped at 4096 characters by Discord
+	CommentMaxLen     = min(getEnvInt("COMMENT_MAX_LEN", 4096), 4096)
+	DescriptionMaxLen = min(getEnvInt("DESCRIPTION_MAX_LEN", 4096), 4096)
 )
 
 var priorities = map[string]string{
@@ -33,6 +117,53 @@ var priorities = map[string]string{
 	"none":   "⚫ None",
 }
 
+// Issue relation fields with their added and removed phrasing
+var relationVerbs = map[string][2]string{
+	"blocking":   {"blocks", "no longer blocks"},
+	"blocked_by": {"is blocked by", "is no longer blocked by"},
+	"duplicate":  {"duplicates", "no longer duplicates"},
+	"relates_to": {"relates to", "no longer relates to"},
+}
+
+// Embed colors
+const (
+	colorCreated  = 8184715
+	colorUpdated  = 4093438
+	colorDeleted  = 16415088
+	colorMuted    = 9807270
+	colorRestored = 5763719
+	colorWarning  = 16753920
+)
+
+// DefaultColor fills any embed left without a color, which Discord would render black.
+// Accepts "#5865F2", "0x5865F2" or a decimal value.
+var DefaultColor = getEnvColor("DEFAULT_COLOR", colorUpdated)
+
+// Emoji prepended to the author line per action, overridable via
+// EVENT_EMOJIS="created=🆕,commented=💬"; an empty value disables one.
+var eventEmojis = func() map[string]string {
+	m := map[string]string{
+		"created":   "🆕",
+		"updated":   "✏️",
+		"deleted":   "🗑️",
+		"commented": "💬",
+	}
+	for k, v := range getEnvMap("EVENT_EMOJIS") {
+		m[k] = v
+	}
+	return m
+}()
+
+// Icon URLs per "event.action" or "event", e.g. EVENT_ICONS="issue.deleted=https://...,issue_comment=https://..."
+var eventIcons = getEnvMap("EVENT_ICONS")
+
+func eventIcon(event, action string) string {
+	if icon, ok := eventIcons[event+"."+action]; ok {
+		return icon
+	}
+	return eventIcons[event]
+}
+
 // Thread-safe map for spam protection
 var (
 	lastUpdated = make(map[string]int64)
@@ -42,12 +173,17 @@ var (
 // --- Discord Payload Structures ---
 type DiscordEmbed struct {
 	Title       string       `json:"title,omitempty"`
+	URL         string       `json:"url,omitempty"`
 	Description string       `json:"description,omitempty"`
 	Color       int          `json:"color"`
 	Author      *EmbedAuthor `json:"author,omitempty"`
 	Thumbnail   *EmbedImage  `json:"thumbnail,omitempty"`
+	Image       *EmbedImage  `json:"image,omitempty"`
 	Footer      *EmbedFooter `json:"footer,omitempty"`
 	Fields      []EmbedField `json:"fields,omitempty"`
+	Timestamp   string       `json:"timestamp,omitempty"`
+
+	changeLines bool // Description lists one change per line, e.g. an assignee diff
 }
 
 type EmbedAuthor struct {
@@ -68,6 +204,8 @@ type EmbedField struct {
 	Name   string `json:"name"`
 	Value  string `json:"value"`
 	Inline bool   `json:"inline"`
+
+	change bool // a change, or issue state, named after its field; kept by the compact and plain styles
 }
 
 // --- Logic ---
@@ -76,40 +214,836 @@ func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
 	}
+	if value, ok := configFileValue(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(getEnv(key, "")); err == nil {
+		return v
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if v, err := time.ParseDuration(getEnv(key, "")); err == nil {
+		return v
+	}
 	return fallback
 }
 
+func getEnvColor(key string, fallback int) int {
+	raw := strings.TrimSpace(getEnv(key, ""))
+	base := 10
+	if hex := strings.TrimPrefix(strings.TrimPrefix(raw, "#"), "0x"); hex != raw {
+		raw, base = hex, 16
+	}
+	if n, err := strconv.ParseInt(raw, base, 32); err == nil {
+		return int(n)
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if v, err := strconv.ParseBool(getEnv(key, "")); err == nil {
+		return v
+	}
+	return fallback
+}
+
+// getEnvMap parses "key=value,key2=value2" into a map with lowercased keys.
+func getEnvMap(key string) map[string]string {
+	m := make(map[string]string)
+	for _, item := range getEnvList(key) {
+		if k, v, ok := strings.Cut(item, "="); ok {
+			m[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+// getEnvList splits a comma-separated env var into trimmed, non-empty items.
+func getEnvList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// assigneeNames returns the display names from the issue's assignee details,
+// resolving bare IDs when the payload carries no detail objects.
+func assigneeNames(data map[string]interface{}) []string {
+	assignees, _ := data["assignees"].([]interface{})
+	var names []string
+	var ids []string
+	for _, a := range assignees {
+		switch v := a.(type) {
+		case map[string]interface{}:
+			if dname, ok := v["display_name"].(string); ok {
+				names = append(names, dname)
+			}
+		case string:
+			ids = append(ids, v)
+		}
+	}
+	if len(names) == 0 && len(ids) == 0 {
+		raw, _ := data["assignee_ids"].([]interface{})
+		for _, id := range raw {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+	}
+	for _, id := range ids {
+		if name, ok := userName(id); ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("Unknown user (%s)", truncate(id, 8)))
+		}
+	}
+	return names
+}
+
+// labelNames lists an issue's label names in payload order.
+func labelNames(data map[string]interface{}) []string {
+	var names []string
+	for _, key := range []string{"labels", "label_details"} {
+		raw, _ := data[key].([]interface{})
+		for _, l := range raw {
+			if label, ok := l.(map[string]interface{}); ok {
+				if name, _ := label["name"].(string); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		if len(names) > 0 {
+			break
+		}
+	}
+	return names
+}
+
+// createdSummary renders the context block of a created issue, one line per attribute.
+func createdSummary(data map[string]interface{}) string {
+	state := "None"
+	if st, ok := data["state"].(map[string]interface{}); ok {
+		if n, _ := st["name"].(string); n != "" {
+			state = n
+		}
+	}
+	prio, _ := data["priority"].(string)
+	assignees := "None"
+	if names := assigneeNames(data); len(names) > 0 {
+		assignees = joinNames(names)
+	}
+	labels := "None"
+	if names := labelNames(data); len(names) > 0 {
+		labels = strings.Join(names, ", ")
+	}
+	return truncate(fmt.Sprintf("**State:** %s\n**Priority:** %s\n**Assignees:** %s\n**Labels:** %s",
+		state, priorityLabel(prio), assignees, labels), 1024)
+}
+
+// issueStateEmbed renders an issue as it is now, for EDIT_IN_PLACE to replace
+// the stored message with; the update's own diff stays out of it.
+func issueStateEmbed(data map[string]interface{}, update DiscordEmbed) DiscordEmbed {
+	name, _ := data["name"].(string)
+	embed := DiscordEmbed{
+		Title:     issueTitle(data, name),
+		URL:       update.URL,
+		Color:     colorUpdated,
+		Author:    update.Author,
+		Thumbnail: update.Thumbnail,
+		Footer:    update.Footer,
+		Timestamp: update.Timestamp,
+	}
+	desc, _ := data["description_stripped"].(string)
+	embed.Description = truncate(desc, DescriptionMaxLen)
+
+	state := "None"
+	if st, ok := data["state"].(map[string]interface{}); ok {
+		if n, _ := st["name"].(string); n != "" {
+			state = n
+		}
+	}
+	prio, _ := data["priority"].(string)
+	embed.Fields = []EmbedField{
+		{Name: "State", Value: state, Inline: true, change: true},
+		{Name: "Priority", Value: priorityLabel(prio), Inline: true, change: true},
+	}
+	if names := assigneeNames(data); len(names) > 0 {
+		embed.Fields = append(embed.Fields, EmbedField{Name: "Assignees", Value: joinNames(names), Inline: true, change: true})
+	}
+	return embed
+}
+
+// issueIdentifier builds the human-readable key (e.g. "ENG-42") for an issue payload.
+func issueIdentifier(data map[string]interface{}) string {
+	project, _ := data["project_detail"].(map[string]interface{})
+	prefix, _ := project["identifier"].(string)
+	seq, ok := data["sequence_id"].(float64)
+	if prefix == "" || !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s-%d", prefix, int(seq))
+}
+
+func projectNameOf(data map[string]interface{}) string {
+	project, _ := data["project_detail"].(map[string]interface{})
+	name, _ := project["name"].(string)
+	return name
+}
+
+// eventProjectName names the project of any event. Comment, link and
+// subscriber payloads lack project_detail, so it falls back to the issue's
+// detail and then to looking the project ID up through the Plane API.
+func eventProjectName(data map[string]interface{}) string {
+	if name := projectNameOf(data); name != "" {
+		return name
+	}
+	issue, _ := data["issue_detail"].(map[string]interface{})
+	if name := projectNameOf(issue); name != "" {
+		return name
+	}
+	id := projectIDOf(data)
+	if isUnset(id) && issue != nil {
+		id = projectIDOf(issue)
+	}
+	name, _ := projectName(id)
+	return name
+}
+
+// issueTitle expands TITLE_TEMPLATE for an issue embed.
+func issueTitle(data map[string]interface{}, name string) string {
+	r := strings.NewReplacer(
+		"{identifier}", issueIdentifier(data),
+		"{name}", name,
+		"{project}", projectNameOf(data),
+	)
+	return strings.TrimSpace(r.Replace(TitleTemplate))
+}
+
+// parentLabel renders a sub-issue's parent as "ENG-1 (Epic name)".
+func parentLabel(data map[string]interface{}) string {
+	parent, ok := data["parent_detail"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	ident := issueIdentifier(parent)
+	if _, ok := parent["project_detail"]; !ok && ident == "" {
+		// Parents usually live in the same project as the issue
+		withProject := map[string]interface{}{"project_detail": data["project_detail"], "sequence_id": parent["sequence_id"]}
+		ident = issueIdentifier(withProject)
+	}
+	name, _ := parent["name"].(string)
+	switch {
+	case ident != "" && name != "":
+		return fmt.Sprintf("%s (%s)", ident, truncate(name, 200))
+	case ident != "":
+		return ident
+	default:
+		return truncate(name, 200)
+	}
+}
+
+func isCustomField(field string) bool {
+	for _, f := range CustomFields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// relationChange renders a relation activity as "ENG-3 blocks ENG-9", or the
+// "no longer" form when the relation was removed.
+func relationChange(data, activity map[string]interface{}, verbs [2]string, oldV, newV string) string {
+	verb, _ := activity["verb"].(string)
+	removed := isUnset(newV) || verb == "deleted" || verb == "removed"
+	other := newV
+	phrase := verbs[0]
+	if removed {
+		other = oldV
+		phrase = verbs[1]
+	}
+	self := issueIdentifier(data)
+	if self == "" {
+		self, _ = data["name"].(string)
+	}
+	return fmt.Sprintf("🔗 **%s** %s **%s**", self, phrase, other)
+}
+
+// parentCommentSnippet returns a one-line excerpt of the comment being replied to.
+func parentCommentSnippet(data map[string]interface{}) string {
+	for _, key := range []string{"parent_comment_detail", "parent_comment"} {
+		if parent, ok := data[key].(map[string]interface{}); ok {
+			if text, ok := parent["comment_stripped"].(string); ok && text != "" {
+				return truncate(strings.Join(strings.Fields(cleanMentions(text)), " "), 150)
+			}
+		}
+	}
+	return ""
+}
+
+// parseDate reads a Plane date, which is either a plain date or a full timestamp.
+func parseDate(v string) (time.Time, bool) {
+	if t, err := time.ParseInLocation("2006-01-02", v, location); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t.In(location), true
+	}
+	return time.Time{}, false
+}
+
+// formatDate renders a Plane date for display in TIMEZONE, e.g. "Mon, Jan 2 2006".
+func formatDate(v string) string {
+	if isUnset(v) {
+		return "None"
+	}
+	t, ok := parseDate(v)
+	if !ok {
+		return v
+	}
+	return t.Format("Mon, Jan 2 2006")
+}
+
+// estimateLabel renders an issue's estimate. Newer Plane versions reference
+// estimate points by ID, so a bare ID without detail is not shown.
+func estimateLabel(data map[string]interface{}) string {
+	if detail, ok := data["estimate_point_detail"].(map[string]interface{}); ok {
+		for _, key := range []string{"value", "key"} {
+			if v := fmt.Sprintf("%v", detail[key]); !isUnset(v) {
+				return v
+			}
+		}
+	}
+	switch v := data["estimate_point"].(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		if len(v) == 36 && strings.Count(v, "-") == 4 {
+			return ""
+		}
+		return v
+	}
+	return ""
+}
+
+// priorityLabel renders a Plane priority key, treating missing values as "none".
+func priorityLabel(p string) string {
+	// Some Plane versions send "Urgent" rather than "urgent"
+	label, ok := priorities[strings.ToLower(strings.TrimSpace(p))]
+	if !ok {
+		label = priorities["none"]
+	}
+	emoji, text, _ := strings.Cut(label, " ")
+	switch PriorityStyle {
+	case "emoji":
+		return emoji
+	case "text":
+		return text
+	}
+	return label
+}
+
+// actorDisplayName picks the best available name for an actor; guests and
+// external reporters often lack a display_name.
+func actorDisplayName(actor map[string]interface{}) string {
+	if name, _ := actor["display_name"].(string); name != "" {
+		return name
+	}
+	first, _ := actor["first_name"].(string)
+	last, _ := actor["last_name"].(string)
+	if full := strings.TrimSpace(first + " " + last); full != "" {
+		return full
+	}
+	email, _ := actor["email"].(string)
+	return email
+}
+
+// creatorOf builds an actor from data.created_by, which Plane sends either as
+// a user object or a bare user ID.
+func creatorOf(data map[string]interface{}) map[string]interface{} {
+	switch v := data["created_by"].(type) {
+	case map[string]interface{}:
+		return v
+	case string:
+		if v == "" {
+			return nil
+		}
+		creator := map[string]interface{}{"id": v}
+		if name, ok := userName(v); ok {
+			creator["display_name"] = name
+		}
+		return creator
+	}
+	return nil
+}
+
+// isSystemActor reports whether an event was triggered by automation rather
+// than a person. A missing actor or name is not evidence of automation.
+func isSystemActor(actor map[string]interface{}) bool {
+	if bot, _ := actor["is_bot"].(bool); bot {
+		return true
+	}
+	return actorMatches(actor, SystemActors)
+}
+
+// actorAvatar picks the author icon: the actor's reachable avatar, the system
+// actor icon, or a generated fallback.
+func actorAvatar(actor map[string]interface{}, name string, system bool) string {
+	if system {
+		if SystemActorIconURL != "" {
+			return SystemActorIconURL
+		}
+	} else if icon := avatarOf(actor); icon != "" && avatarReachable(icon) {
+		return icon
+	}
+	if name == "" {
+		return ""
+	}
+	return fallbackAvatar(name)
+}
+
+// Reachability of external avatars, cached since the same actors recur.
+// The cache holds at most maxAvatarChecks entries.
+const maxAvatarChecks = 1000
+
+var (
+	avatarChecks   = map[string]avatarCheck{}
+	avatarChecksMu sync.Mutex
+	avatarClient   = &http.Client{Timeout: 2 * time.Second}
+)
+
+type avatarCheck struct {
+	ok bool
+	at time.Time
+}
+
+// avatarReachable reports whether an avatar outside APP_URL answers a HEAD
+// request with an image. Results are cached for an hour.
+func avatarReachable(avatar string) bool {
+	if !CheckAvatarURLs || strings.HasPrefix(avatar, AppURL) {
+		return true
+	}
+	avatarChecksMu.Lock()
+	if c, ok := avatarChecks[avatar]; ok && time.Since(c.at) < time.Hour {
+		avatarChecksMu.Unlock()
+		return c.ok
+	}
+	avatarChecksMu.Unlock()
+
+	ok := false
+	if resp, err := avatarClient.Head(avatar); err == nil {
+		resp.Body.Close()
+		ok = resp.StatusCode < 300 && strings.HasPrefix(resp.Header.Get("Content-Type"), "image/")
+	}
+	if !ok {
+		log.Printf("[WARN] Avatar %s is unreachable, using the fallback", avatar)
+	}
+	avatarChecksMu.Lock()
+	if len(avatarChecks) >= maxAvatarChecks {
+		for k, c := range avatarChecks {
+			if time.Since(c.at) >= time.Hour {
+				delete(avatarChecks, k)
+			}
+		}
+		// Still full of fresh entries: start over rather than grow
+		if len(avatarChecks) >= maxAvatarChecks {
+			avatarChecks = map[string]avatarCheck{}
+		}
+	}
+	avatarChecks[avatar] = avatarCheck{ok: ok, at: time.Now()}
+	avatarChecksMu.Unlock()
+	return ok
+}
+
+// fallbackAvatar returns an icon for an actor without an avatar, or "" to keep the plane icon.
+func fallbackAvatar(name string) string {
+	if AvatarIdenticons {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		return fmt.Sprintf("https://ui-avatars.com/api/?name=%s&background=%06x&color=fff",
+			url.QueryEscape(name), h.Sum32()&0xffffff)
+	}
+	return DefaultActorAvatarURL
+}
+
+// assigneeChange renders the old and new sides of an assignee change.
+func assigneeChange(data map[string]interface{}, oldV string) (string, string) {
+	newV := "None"
+	if names := assigneeNames(data); len(names) > 0 {
+		newV = joinNames(names)
+	}
+	if isUnset(oldV) {
+		oldV = "None"
+	} else {
+		oldV = "Previously set"
+	}
+	return oldV, newV
+}
+
+// userIDs reads a list of user IDs from an activity value, which Plane sends
+// as a JSON list, a comma-separated string or a single ID.
+func userIDs(v interface{}) []string {
+	var ids []string
+	switch val := v.(type) {
+	case []interface{}:
+		for _, item := range val {
+			if m, ok := item.(map[string]interface{}); ok {
+				item = m["id"]
+			}
+			if s, ok := item.(string); ok && s != "" {
+				ids = append(ids, strings.ToLower(s))
+			}
+		}
+	case string:
+		for _, s := range strings.Split(strings.Trim(val, "[]"), ",") {
+			if s = strings.Trim(strings.TrimSpace(s), `"'`); s != "" {
+				ids = append(ids, strings.ToLower(s))
+			}
+		}
+	}
+	return ids
+}
+
+// assigneeDiff renders who was assigned and unassigned, e.g. "Alice was
+// assigned"; "" when the activity lacks the previous assignees.
+func assigneeDiff(data map[string]interface{}, oldRaw interface{}) string {
+	if isUnset(fmt.Sprintf("%v", oldRaw)) {
+		return ""
+	}
+	current := userIDs(data["assignee_ids"])
+	if len(current) == 0 {
+		current = userIDs(data["assignees"])
+	}
+	names := make(map[string]string)
+	assignees, _ := data["assignees"].([]interface{})
+	for _, a := range assignees {
+		if m, ok := a.(map[string]interface{}); ok {
+			if id, _ := m["id"].(string); id != "" {
+				names[strings.ToLower(id)] = actorDisplayName(m)
+			}
+		}
+	}
+	nameOf := func(id string) string {
+		if n := names[id]; n != "" {
+			return n
+		}
+		if n, ok := userName(id); ok {
+			return n
+		}
+		return fmt.Sprintf("Unknown user (%s)", truncate(id, 8))
+	}
+
+	was := make(map[string]bool)
+	for _, id := range userIDs(oldRaw) {
+		was[id] = true
+	}
+	var lines []string
+	for _, id := range current {
+		if !was[id] {
+			lines = append(lines, fmt.Sprintf("➕ **%s** was assigned", nameOf(id)))
+		}
+		delete(was, id)
+	}
+	removed := make([]string, 0, len(was))
+	for id := range was {
+		removed = append(removed, id)
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		lines = append(lines, fmt.Sprintf("➖ **%s** was unassigned", nameOf(id)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// assigneeAvatar returns the avatar of the assignee with the lowest ID so the
+// thumbnail stays the same regardless of the order Plane lists them in.
+func assigneeAvatar(data map[string]interface{}) string {
+	assignees, _ := data["assignees"].([]interface{})
+	var pick map[string]interface{}
+	for _, a := range assignees {
+		amap, ok := a.(map[string]interface{})
+		if !ok || avatarOf(amap) == "" {
+			continue
+		}
+		if pick == nil || fmt.Sprint(amap["id"]) < fmt.Sprint(pick["id"]) {
+			pick = amap
+		}
+	}
+	if pick == nil {
+		return ""
+	}
+	return avatarOf(pick)
+}
+
+// projectIcon returns the project's logo URL, or the Plane icon when it has none.
+func projectIcon(data map[string]interface{}) string {
+	project, _ := data["project_detail"].(map[string]interface{})
+	for _, key := range []string{"logo_url", "icon_url"} {
+		if u, _ := project[key].(string); u != "" {
+			if u[0] == '/' {
+				u = AppURL + u
+			}
+			return u
+		}
+	}
+	return fmt.Sprintf("%s/img/plane-icon.png", AppURL)
+}
+
+// setThumbnail applies the THUMBNAIL_SOURCE policy to an embed.
+func setThumbnail(embed *DiscordEmbed, data map[string]interface{}, actorIcon string) {
+	var src string
+	switch ThumbnailSource {
+	case "assignee":
+		src = assigneeAvatar(data)
+	case "actor":
+		src = actorIcon
+	case "project-icon":
+		src = projectIcon(data)
+	}
+	if src == "" {
+		return
+	}
+	// Discord rejects the whole embed over a malformed image URL, so drop it instead
+	if !validImageURL(src) {
+		log.Printf("[WARN] Dropping invalid thumbnail URL %q", src)
+		return
+	}
+	embed.Thumbnail = &EmbedImage{URL: src}
+}
+
+// validImageURL reports whether u is an absolute http(s) URL Discord can fetch.
+func validImageURL(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// avatarOf returns a user's absolute avatar URL, or "" if none is set.
+func avatarOf(user map[string]interface{}) string {
+	avatar, _ := user["avatar"].(string)
+	if avatar == "" {
+		avatar, _ = user["avatar_url"].(string)
+	}
+	if avatar != "" && avatar[0] == '/' {
+		avatar = fmt.Sprintf("%s%s", AppURL, avatar)
+	}
+	return avatar
+}
+
+// joinNames lists names up to MAX_ASSIGNEES_SHOWN, summarizing the rest.
+func joinNames(names []string) string {
+	if MaxAssigneesShown > 0 && len(names) > MaxAssigneesShown {
+		hidden := len(names) - MaxAssigneesShown
+		return fmt.Sprintf("%s +%d more", strings.Join(names[:MaxAssigneesShown], ", "), hidden)
+	}
+	return strings.Join(names, ", ")
+}
+
+// isUnset reports whether a stringified activity value carries no data.
+func isUnset(v string) bool {
+	return v == "" || v == "[]" || v == "null" || v == "<nil>"
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if n <= 0 || len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// HMAC hash constructors selectable via SIGNATURE_ALGO
+var signatureHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha1":   sha1.New,
+}
+
+// computeSignature returns the hex HMAC of body as Plane sends it in x-plane-signature.
+func computeSignature(secret string, body []byte) string {
+	h := hmac.New(signatureHashes[SignatureAlgo], []byte(secret))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// verifySignature checks the HMAC over body, which must be the exact bytes read
+// from the request before any parsing; re-encoded JSON will not match.
 func verifySignature(body []byte, signature string) bool {
 	if WebhookSecret == "" {
 		return true
 	} // Warning: Security disabled
-	h := hmac.New(sha256.New, []byte(WebhookSecret))
-	h.Write(body)
-	expected := hex.EncodeToString(h.Sum(nil))
-	return hmac.Equal([]byte(expected), []byte(signature))
+	expected := computeSignature(WebhookSecret, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		prefix := signature
+		if len(prefix) > 8 {
+			prefix = prefix[:8]
+		}
+		log.Printf("[DEBUG] Signature mismatch: expected %d hex chars, received %d (starts with %q), body %d bytes",
+			len(expected), len(signature), prefix, len(body))
+		return false
+	}
+	return true
+}
+
+// webhookResult is the JSON body returned to Plane describing what the bridge did.
+type webhookResult struct {
+	Status string `json:"status"`
+	Event  string `json:"event,omitempty"`
+	Action string `json:"action,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func respond(w http.ResponseWriter, code int, res webhookResult) {
+	if code == http.StatusNoContent {
+		w.WriteHeader(code)
+		return
+	}
+	writeJSON(w, code, res)
 }
 
-func sendToDiscord(embed DiscordEmbed) {
-	payload := map[string]interface{}{
-		"username":   "Plane",
-		"avatar_url": fmt.Sprintf("%s/img/plane-icon.png", AppURL),
-		"embeds":     []DiscordEmbed{embed},
+// debounced reports whether key (see dedupKey) was already updated within the
+// last 2 seconds of now, recording now as its last update otherwise.
+func debounced(key string, now time.Time) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	if now.Unix() < lastUpdated[key]+2 {
+		return true
 	}
-	body, _ := json.Marshal(payload)
-	resp, err := http.Post(DiscordURL, "application/json", bytes.NewBuffer(body))
-	if err != nil {
-		log.Printf("Error sending to Discord: %v", err)
+	lastUpdated[key] = now.Unix()
+	return false
+}
+
+// Assignments arriving this soon after an issue's creation are folded into the creation
+// message. 0, the default, disables folding.
+var (
+	CoalesceCreateWindow = getEnvDuration("COALESCE_CREATE_WINDOW", 0)
+	recentCreates        = make(map[string]time.Time)
+)
+
+// markCreated records when a creation was forwarded for issueID.
+func markCreated(issueID string, now time.Time) {
+	if CoalesceCreateWindow <= 0 {
 		return
 	}
-	defer resp.Body.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	for id, t := range recentCreates {
+		if now.Sub(t) > CoalesceCreateWindow {
+			delete(recentCreates, id)
+		}
+	}
+	recentCreates[issueID] = now
+}
+
+// coalescesCreate reports whether an update only sets assignees on an issue
+// created within COALESCE_CREATE_WINDOW. Each creation is folded at most once.
+func coalescesCreate(issueID string, activities []map[string]interface{}, now time.Time) bool {
+	if CoalesceCreateWindow <= 0 || len(activities) == 0 {
+		return false
+	}
+	for _, a := range activities {
+		if a["field"] != "assignee_ids" {
+			return false
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	created, ok := recentCreates[issueID]
+	if !ok || now.Sub(created) > CoalesceCreateWindow {
+		return false
+	}
+	delete(recentCreates, issueID)
+	return true
+}
+
+// workspaceNameOf prefers the workspace named in the payload, so one bridge
+// serving several workspaces labels each event correctly.
+func workspaceNameOf(p, data map[string]interface{}) string {
+	for _, src := range []interface{}{data["workspace_detail"], p["workspace_detail"], p["workspace"]} {
+		if ws, ok := src.(map[string]interface{}); ok {
+			if name, ok := ws["name"].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return WorkspaceName
+}
+
+// newEmbed returns an embed carrying the default workspace author and footer.
+func newEmbed() DiscordEmbed {
+	return newEmbedFor(WorkspaceName)
+}
+
+// footerText names the bridge instance that sent a message.
+func footerText() string {
+	if Environment != "" {
+		return "Plane Bridge · " + Environment
+	}
+	return "Plane Bridge"
+}
+
+func newEmbedFor(workspace string) DiscordEmbed {
+	return DiscordEmbed{
+		Author: &EmbedAuthor{
+			Name:    workspace,
+			IconURL: fmt.Sprintf("%s/img/plane-icon.png", AppURL),
+		},
+		Footer: &EmbedFooter{
+			Text: footerText(),
+		},
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+var metricSaturated = expvar.NewInt("webhooks_rejected_saturated")
+
+// limitInflight rejects requests with 503 while MAX_INFLIGHT_HANDLERS are being processed.
+func limitInflight(next http.HandlerFunc) http.HandlerFunc {
+	if MaxInflightHandlers <= 0 {
+		return next
+	}
+	slots := make(chan struct{}, MaxInflightHandlers)
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next(w, r)
+		default:
+			metricSaturated.Add(1)
+			log.Printf("[WARN] Rejecting webhook: %d handlers already in flight", MaxInflightHandlers)
+			respond(w, http.StatusServiceUnavailable, webhookResult{Status: "rejected", Reason: "too many requests in flight"})
+		}
+	}
 }
 
 func webhookHandler(w http.ResponseWriter, r *http.Request) {
 	body, _ := io.ReadAll(r.Body)
 
-	if !verifySignature(body, r.Header.Get("x-plane-signature")) {
+	valid := verifySignature(body, r.Header.Get("x-plane-signature"))
+	if SignatureDiagnostics {
+		log.Printf("[INFO] Signature diagnostics: signature valid: %t", valid)
+		// Test requests stop here and report the result instead of being forwarded
+		if r.Header.Get("X-Bridge-Test") != "" {
+			res := webhookResult{Status: "tested", Reason: "signature valid"}
+			if !valid {
+				res.Reason = "signature mismatch"
+			}
+			respond(w, http.StatusOK, res)
+			return
+		}
+	}
+
+	if !valid {
 		log.Println("[WARN] Invalid signature")
-		http.Error(w, "Invalid signature", http.StatusForbidden)
+		respond(w, http.StatusForbidden, webhookResult{Status: "rejected", Reason: "invalid signature"})
 		return
 	}
 
@@ -121,55 +1055,134 @@ func webhookHandler(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[DEBUG] Event: %s | Action: %s | Payload: %s", event, action, string(body))
 
+	skip := func(reason string) {
+		respond(w, SkippedStatusCode, webhookResult{Status: "skipped", Event: event, Action: action, Reason: reason})
+	}
+
+	if forwardingPaused.Load() {
+		metricPaused.Add(1)
+		respond(w, http.StatusOK, webhookResult{Status: "skipped", Event: event, Action: action, Reason: "forwarding paused"})
+		return
+	}
+
+	delivery := deliveryID(r)
+	if deliverySeen(delivery, time.Now()) {
+		log.Printf("[INFO] Skipping redelivered webhook %s", delivery)
+		skip("duplicate delivery")
+		return
+	}
+
 	data, _ := p["data"].(map[string]interface{})
-	activity, _ := p["activity"].(map[string]interface{})
+	activities := activityList(p["activity"])
+	if len(activities) == 0 {
+		// Some Plane versions nest the activity inside data
+		activities = activityList(data["activity"])
+	}
+	var activity map[string]interface{}
+	if len(activities) > 0 {
+		activity = activities[0]
+	}
+	validatePayload(p, activities, event, action)
 
 	// Extract Actor info
 	actor, _ := activity["actor"].(map[string]interface{})
-	actorName, _ := actor["display_name"].(string)
-	actorIcon, _ := actor["avatar"].(string)
-	if actorIcon == "" {
-		actorIcon, _ = actor["avatar_url"].(string)
+	if actor == nil && action == "created" {
+		actor = creatorOf(data)
 	}
-	if actorIcon != "" && len(actorIcon) > 0 && actorIcon[0] == '/' {
-		actorIcon = fmt.Sprintf("%s%s", AppURL, actorIcon)
+	actorName := actorDisplayName(actor)
+	systemActor := SystemActorLabel != "" && isSystemActor(actor)
+	if systemActor {
+		actorName = SystemActorLabel
 	}
 
-	embed := DiscordEmbed{
-		Author: &EmbedAuthor{
-			Name:    WorkspaceName,
-			IconURL: fmt.Sprintf("%s/img/plane-icon.png", AppURL),
-		},
-		Footer: &EmbedFooter{
-			Text: "Plane Bridge",
-		},
+	// Loop guard for bidirectional integrations acting as the bridge
+	if actorID, _ := actor["id"].(string); BridgeActorID != "" && strings.EqualFold(actorID, BridgeActorID) {
+		log.Printf("[INFO] Skipping event %s triggered by the bridge identity", event)
+		skip("bridge actor")
+		return
 	}
 
+	embed := newEmbedFor(workspaceNameOf(p, data))
+	if createdAt, ok := activity["created_at"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+			embed.Timestamp = t.UTC().Format(time.RFC3339)
+		}
+	}
+
+	// Event icons mark the footer, and the author line when there is no actor avatar
+	if icon := eventIcon(event, action); icon != "" {
+		embed.Footer.IconURL = icon
+		embed.Author.IconURL = icon
+	}
 	if actorName != "" {
 		embed.Author.Name = actorName
-		if actorIcon != "" {
-			embed.Author.IconURL = actorIcon
-		}
 	}
 
 	handled := false
+	coalesced := false
+
+	// Deletion payloads carry only the ID, so they cannot be filtered by label
+	if event == "issue" && action != "deleted" && !hasRequiredLabels(data) {
+		log.Printf("[INFO] Skipping issue without required labels: %v", data["id"])
+		skip("missing required labels")
+		return
+	}
+
+	if name, _ := data["name"].(string); event == "issue" && isExcludedName(name) {
+		log.Printf("[INFO] Skipping excluded issue: %s", name)
+		skip("excluded name")
+		return
+	}
 
 	if event == "issue" {
 		handled = true
 		issueID := fmt.Sprintf("%v", data["id"])
 		name, _ := data["name"].(string)
+		if action != "deleted" {
+			embed.URL = issueURL(data, issueID)
+		}
+
+		// Re-render the creation with its assignees instead of a separate update
+		if action == "updated" && coalescesCreate(issueID, activities, time.Now()) {
+			log.Printf("[INFO] Folding assignment of %s into its creation message", issueID)
+			action = "created"
+			coalesced = true
+		}
 
 		switch action {
 		case "created":
+			if !notifiesCreatedState(data) {
+				log.Printf("[INFO] Skipping creation of %s outside NOTIFY_CREATED_STATES", name)
+				skip("state not notified")
+				return
+			}
 			log.Printf("[INFO] Issue Created: %s", name)
 			if actorName != "" {
 				embed.Author.Name = fmt.Sprintf("%s created an issue", actorName)
 			}
-			embed.Color = 8184715
-			embed.Title = name
-			embed.Description = fmt.Sprintf("%v", data["description_stripped"])
+			embed.Color = colorCreated
+			embed.Title = issueTitle(data, name)
+			desc, _ := data["description_stripped"].(string)
+			embed.Description = truncate(desc, DescriptionMaxLen)
 			prio, _ := data["priority"].(string)
-			embed.Fields = append(embed.Fields, EmbedField{Name: "Priority", Value: priorities[prio], Inline: true})
+			if CreatedSummary {
+				embed.Fields = append(embed.Fields, EmbedField{Name: "Summary", Value: createdSummary(data)})
+			} else {
+				embed.Fields = append(embed.Fields, EmbedField{Name: "Priority", Value: priorityLabel(prio), Inline: true})
+			}
+			if est := estimateLabel(data); est != "" {
+				embed.Fields = append(embed.Fields, EmbedField{Name: "Estimate", Value: est, Inline: true})
+			}
+			if (CreatedShowAssignees || coalesced) && !CreatedSummary {
+				if names := assigneeNames(data); len(names) > 0 {
+					embed.Fields = append(embed.Fields, EmbedField{Name: "Assignees", Value: joinNames(names), Inline: true})
+				}
+			}
+			// Imports arrive as creations that are already overdue
+			if due, _ := data["target_date"].(string); isPastDue(due) {
+				embed.Color = colorWarning
+				embed.Description = strings.TrimPrefix(embed.Description+"\n⚠️ **Past due**", "\n")
+			}
 
 		case "deleted":
 			if actorName != "" {
@@ -177,163 +1190,547 @@ func webhookHandler(w http.ResponseWriter, r *http.Request) {
 			} else {
 				embed.Author.Name = "Work item deleted"
 			}
-			embed.Color = 16415088
+			embed.Color = colorDeleted
 			embed.Description = fmt.Sprintf("ID: `%s`", issueID)
 
+		case "archived", "unarchived":
+			ident := issueIdentifier(data)
+			if ident == "" {
+				ident = issueID
+			}
+			embed.Title = issueTitle(data, name)
+			if action == "archived" {
+				if actorName != "" {
+					embed.Author.Name = fmt.Sprintf("%s archived an issue", actorName)
+				}
+				embed.Color = colorMuted
+				embed.Description = fmt.Sprintf("📦 Archived `%s`", ident)
+			} else {
+				if actorName != "" {
+					embed.Author.Name = fmt.Sprintf("%s restored an issue", actorName)
+				}
+				embed.Color = colorRestored
+				embed.Description = fmt.Sprintf("♻️ Restored `%s`", ident)
+			}
+
 		case "updated":
-			// Anti-spam: check if this ID was updated in the last 2 seconds
-			mu.Lock()
-			now := time.Now().Unix()
-			if now < lastUpdated[issueID]+2 {
-				mu.Unlock()
+			// Checked before debouncing so a reorder cannot swallow a real change
+			if isReorder(activities) {
+				skip("ordering change")
+				return
+			}
+			// Redeliveries were caught above; without a delivery ID fall back to the issue
+			if delivery == "" && debounced(dedupKey(issueID, activities), time.Now()) {
+				skip("debounced")
+				return
+			}
+			if activity == nil {
+				log.Printf("[WARN] Skipping update of issue %s: payload has no activity object", issueID)
+				skip("missing activity")
 				return
 			}
-			lastUpdated[issueID] = now
-			mu.Unlock()
 
-			field := fmt.Sprintf("%v", activity["field"])
+			// A multi-field save arrives as an activity array; list every change in one embed
+			if MergeActivities && len(activities) > 1 {
+				embed.Color = colorUpdated
+				embed.Title = issueTitle(data, name)
+				var changes []EmbedField
+				pastDue := false
+				resolved := ""
+				for _, a := range activities {
+					if c, ok := describeChange(data, a); ok && !(SkipNoopUpdates && c.noop) {
+						changes = append(changes, changeField(data, c))
+						pastDue = pastDue || c.pastDue
+						if c.resolved != "" {
+							resolved = c.resolved
+						}
+						if c.image != "" && validImageURL(c.image) {
+							embed.Image = &EmbedImage{URL: c.image}
+						}
+					}
+				}
+				if len(changes) == 0 {
+					skip("field not tracked")
+					return
+				}
+				embed.Description = fmt.Sprintf("%d fields changed.", len(changes))
+				embed.Fields = append(embed.Fields, collapseFields(changes, MaxChangeFields, "+%d more changes")...)
+				if pastDue {
+					embed.Color = colorWarning
+					embed.Description += "\n⚠️ **Past due**"
+				}
+				if resolved != "" {
+					embed.Description += "\n✅ Resolved in " + resolved
+				}
+				break
+			}
 
-			// Whitelist
-			allowed := map[string]bool{
-				"name":           true,
-				"priority":       true,
-				"state":          true,
-				"state_id":       true,
-				"assignee_ids":   true,
-				"target_date":    true,
-				"parent":         true,
-				"estimate_point": true,
+			change, ok := describeChange(data, activity)
+			if !ok {
+				log.Printf("[DEBUG] Skipping update of untracked field %v on issue %s", activity["field"], issueID)
+				skip("field not tracked")
+				return
 			}
-			if !allowed[field] {
+			if SkipNoopUpdates && change.noop {
+				log.Printf("[DEBUG] Skipping no-op update of %s on issue %s", change.label, issueID)
+				skip("no-op change")
 				return
 			}
+			field, oldV, newV := change.label, change.oldV, change.newV
 
-			embed.Color = 4093438
-			embed.Title = name
-
-			oldV := fmt.Sprintf("%v", activity["old_value"])
-			newV := fmt.Sprintf("%v", activity["new_value"])
+			embed.Color = colorUpdated
+			embed.Title = issueTitle(data, name)
 
-			if field == "priority" {
-				oldV = priorities[oldV]
-				newV = priorities[newV]
+			if verbs, ok := relationVerbs[field]; ok {
+				embed.Description = relationChange(data, activity, verbs, oldV, newV)
+				break
 			}
 
-			if field == "state_id" || field == "state" {
-				field = "State"
-				if st, ok := data["state"].(map[string]interface{}); ok {
-					newV, _ = st["name"].(string)
+			// Publishing a draft is when the issue first becomes visible to the team
+			if field == "Draft" {
+				if newV == "true" {
+					skip("moved to drafts")
+					return
 				}
-				if oldV == "null" || oldV == "<nil>" {
-					oldV = "None"
-				} else {
-					oldV = "Changed"
+				if actorName != "" {
+					embed.Author.Name = fmt.Sprintf("%s published an issue", actorName)
 				}
+				embed.Color = colorCreated
+				embed.Description = "📢 Issue published"
+				break
 			}
 
-			if field == "assignee_ids" {
-				field = "Assignees"
-				assignees, _ := data["assignees"].([]interface{})
-				var names []string
-				for _, a := range assignees {
-					if amap, ok := a.(map[string]interface{}); ok {
-						if dname, ok := amap["display_name"].(string); ok {
-							names = append(names, dname)
-						}
-					}
-				}
-				newV = "None"
-				if len(names) > 0 {
-					newV = ""
-					for i, n := range names {
-						if i > 0 {
-							newV += ", "
-						}
-						newV += n
-					}
-				}
-				if oldV == "[]" || oldV == "null" || oldV == "<nil>" {
-					oldV = "None"
-				} else {
-					oldV = "Previously set"
-				}
+			if change.diff != "" {
+				embed.Description = change.diff
+				embed.changeLines = true
+				break
+			}
 
-				// If we have assignees, set the first one's avatar as thumbnail
-				if len(assignees) > 0 {
-					if first, ok := assignees[0].(map[string]interface{}); ok {
-						favatar, _ := first["avatar"].(string)
-						if favatar == "" {
-							favatar, _ = first["avatar_url"].(string)
-						}
-						if favatar != "" {
-							if favatar[0] == '/' {
-								favatar = fmt.Sprintf("%s%s", AppURL, favatar)
-							}
-							embed.Thumbnail = &EmbedImage{URL: favatar}
-						}
-					}
+			if field == "Project" {
+				embed.Description = fmt.Sprintf("📦 Moved from **%s** to **%s**", oldV, newV)
+				break
+			}
+
+			// Titles can be long, so renames get their own layout instead of title + diff
+			if field == "name" {
+				if actorName != "" {
+					embed.Author.Name = fmt.Sprintf("%s renamed an issue", actorName)
 				}
+				embed.Title = "✏️ Renamed"
+				if ident := issueIdentifier(data); ident != "" {
+					embed.Title += " " + ident
+				}
+				embed.Fields = append(embed.Fields,
+					EmbedField{Name: "From", Value: truncate(oldV, 256), change: true},
+					EmbedField{Name: "To", Value: truncate(newV, 256), change: true},
+				)
+				break
 			}
 
 			embed.Description = fmt.Sprintf("Field **%s** changed.", field)
+			if change.custom {
+				embed.Description = fmt.Sprintf("Custom field **%s** changed.", field)
+			}
+			if text := activityText(activity); UseActivityText && text != "" {
+				embed.Description = truncate(text, DescriptionMaxLen)
+			}
 			embed.Fields = append(embed.Fields, EmbedField{
 				Name:  "Change",
 				Value: fmt.Sprintf("`%s` → `%s`", oldV, newV),
 			})
+			if change.pastDue {
+				embed.Color = colorWarning
+				embed.Description += "\n⚠️ **Past due**"
+			}
+			if change.resolved != "" {
+				embed.Description += "\n✅ Resolved in " + change.resolved
+			}
+			if change.image != "" && validImageURL(change.image) {
+				embed.Image = &EmbedImage{URL: change.image}
+			}
+		case "assigned", "unassigned":
+			// Some Plane versions send explicit actions instead of an assignee_ids update
+			if actorName != "" {
+				embed.Author.Name = fmt.Sprintf("%s %s an issue", actorName, action)
+			}
+			embed.Color = colorUpdated
+			embed.Title = issueTitle(data, name)
+			if diff := assigneeDiff(data, activity["old_value"]); diff != "" {
+				embed.Description = diff
+				embed.changeLines = true
+				break
+			}
+			oldV, newV := assigneeChange(data, fmt.Sprintf("%v", activity["old_value"]))
+			embed.Description = "Field **Assignees** changed."
+			embed.Fields = append(embed.Fields, EmbedField{
+				Name:  "Change",
+				Value: fmt.Sprintf("`%s` → `%s`", oldV, newV),
+			})
+
 		default:
-			return // Ignore other actions for issues
+			skip("action not handled") // Ignore other actions for issues
+			return
+		}
+
+		if action == "created" || action == "updated" {
+			if parent := parentLabel(data); parent != "" {
+				embed.Fields = append(embed.Fields, EmbedField{Name: "Parent", Value: parent, Inline: true})
+			}
+			if st, ok := data["state"].(map[string]interface{}); ok && ShowStatusField {
+				if state, _ := st["name"].(string); state != "" {
+					embed.Fields = append(embed.Fields, EmbedField{Name: "Status", Value: state, Inline: true})
+				}
+			}
+		}
+		if action == "updated" && IncludeDescriptionOnUpdate {
+			// Field values are capped at 1024 characters
+			if desc, _ := data["description_stripped"].(string); strings.TrimSpace(desc) != "" {
+				embed.Fields = append(embed.Fields, EmbedField{Name: "Description", Value: truncate(desc, min(DescriptionMaxLen, 1024))})
+			}
 		}
 	} else if event == "issue_comment" {
+		if actorMatches(actor, IgnoredCommentActors) {
+			log.Printf("[INFO] Skipping comment by ignored actor: %s", actorName)
+			skip("ignored comment actor")
+			return
+		}
 		handled = true
-		embed.Color = 8184715
+		embed.Color = colorCreated
 		if actorName != "" {
 			embed.Author.Name = fmt.Sprintf("%s commented", actorName)
 		} else {
 			embed.Author.Name = "New Comment"
 		}
 		comment, _ := data["comment_stripped"].(string)
-		embed.Description = comment
+		comment = cleanMentions(comment)
+		embed.Description = truncate(comment, CommentMaxLen)
 
 		issueID := fmt.Sprintf("%v", data["issue"])
 		issueName := "Issue Update"
-		if issue, ok := data["issue_detail"].(map[string]interface{}); ok {
-			if n, ok := issue["name"].(string); ok {
-				issueName = n
-			}
+		issue, ok := data["issue_detail"].(map[string]interface{})
+		if !ok {
+			issue = map[string]interface{}{}
+		}
+		if n, ok := issue["name"].(string); ok {
+			issueName = n
+		}
+		if isUnset(projectIDOf(issue)) {
+			issue["project"] = data["project"]
 		}
 		embed.Title = issueName
+		embed.URL = issueURL(issue, issueID)
 		embed.Fields = append(embed.Fields, EmbedField{Name: "Issue ID", Value: issueID, Inline: true})
+
+		if !isUnset(fmt.Sprintf("%v", data["parent_comment"])) {
+			ref := issueIdentifier(issue)
+			if ref == "" {
+				ref = issueName
+			}
+			if actorName != "" {
+				embed.Author.Name = fmt.Sprintf("%s replied to a comment on %s", actorName, ref)
+			} else {
+				embed.Author.Name = fmt.Sprintf("Reply on %s", ref)
+			}
+			if quote := parentCommentSnippet(data); quote != "" {
+				embed.Description = truncate("> "+quote+"\n\n"+comment, CommentMaxLen)
+			}
+		}
+	}
+
+	if (event == "cycle" || event == "module") && action == "created" {
+		handled = true
+		name, _ := data["name"].(string)
+		if actorName != "" {
+			embed.Author.Name = fmt.Sprintf("%s created a %s", actorName, event)
+		}
+		embed.Color = colorCreated
+		embed.Title = name
+		desc, _ := data["description_stripped"].(string)
+		if desc == "" {
+			desc, _ = data["description"].(string)
+		}
+		embed.Description = truncate(desc, DescriptionMaxLen)
+		if event == "cycle" {
+			embed.URL = cycleURL(projectIDOf(data), fmt.Sprintf("%v", data["id"]))
+		}
+
+		// Cycles end on end_date, modules on target_date
+		endKey := "end_date"
+		if event == "module" {
+			endKey = "target_date"
+		}
+		embed.Fields = append(embed.Fields,
+			EmbedField{Name: "Start", Value: formatDate(fmt.Sprintf("%v", data["start_date"])), Inline: true},
+			EmbedField{Name: "End", Value: formatDate(fmt.Sprintf("%v", data[endKey])), Inline: true},
+		)
+	}
+
+	if event == "view" && NotifyViews && (action == "created" || action == "updated") {
+		handled = true
+		name, _ := data["name"].(string)
+		if name == "" {
+			name = "Untitled view"
+		}
+		embed.Color = colorCreated
+		verb := "New view created"
+		if action == "updated" {
+			embed.Color = colorUpdated
+			verb = "View updated"
+		}
+		embed.Description = fmt.Sprintf("🔎 %s: **%s**", verb, name)
+		if desc, _ := data["description"].(string); desc != "" {
+			embed.Description += "\n" + truncate(desc, DescriptionMaxLen)
+		}
+		if project := projectIDOf(data); !isUnset(project) {
+			embed.URL = projectURL(project)
+		}
+	}
+
+	if event == "issue_subscriber" && NotifySubscriptions {
+		handled = true
+		who := actorName
+		if sub, ok := data["subscriber_detail"].(map[string]interface{}); ok {
+			if n := actorDisplayName(sub); n != "" {
+				who = n
+			}
+		}
+		if who == "" {
+			who = "Someone"
+		}
+		issue, _ := data["issue_detail"].(map[string]interface{})
+		ref := issueIdentifier(issue)
+		if ref == "" {
+			ref, _ = issue["name"].(string)
+		}
+		if ref == "" {
+			ref = fmt.Sprintf("%v", data["issue"])
+		}
+		embed.Color = colorMuted
+		if action == "deleted" {
+			embed.Description = fmt.Sprintf("%s stopped watching **%s**", who, ref)
+		} else {
+			embed.Description = fmt.Sprintf("👀 %s is watching **%s**", who, ref)
+		}
+	}
+
+	if event == "issue_link" && (action == "created" || action == "deleted") {
+		handled = true
+		link, _ := data["url"].(string)
+		issue, _ := data["issue_detail"].(map[string]interface{})
+		ref := issueIdentifier(issue)
+		if ref == "" {
+			ref, _ = issue["name"].(string)
+		}
+		if ref == "" {
+			ref = fmt.Sprintf("%v", data["issue"])
+		}
+		embed.Color = colorUpdated
+		verb := "added to"
+		if action == "deleted" {
+			embed.Color = colorMuted
+			verb = "removed from"
+		}
+		embed.Description = fmt.Sprintf("🔗 Link %s **%s**: %s", verb, ref, link)
+		if title, _ := data["title"].(string); title != "" && title != link {
+			embed.Description += "\n" + truncate(title, 256)
+		}
+		if issue == nil {
+			issue = map[string]interface{}{}
+		}
+		if isUnset(projectIDOf(issue)) {
+			issue["project"] = data["project"]
+		}
+		embed.URL = issueURL(issue, fmt.Sprintf("%v", data["issue"]))
 	}
 
 	if !handled {
 		log.Printf("[INFO] Skipping unhandled event: %s action: %s", event, action)
-		w.WriteHeader(http.StatusOK)
+		skip("event not handled")
 		return
 	}
 
 	// Double check for "empty" content
 	if embed.Title == "" && embed.Description == "" {
 		log.Printf("[WARN] Skipping empty embed for event: %s", event)
-		w.WriteHeader(http.StatusOK)
+		skip("empty embed")
 		return
 	}
 
-	sendToDiscord(embed)
-	w.WriteHeader(http.StatusOK)
+	embed.Description = renderDescription(templateData{
+		Event: event, Action: action, Actor: actorName, Default: embed.Description,
+		Data: data, Activity: activity, Payload: p,
+	})
+
+	// Resolved only now so skipped events never wait on the avatar check
+	actorIcon := actorAvatar(actor, actorName, systemActor)
+	if actorName != "" && actorIcon != "" {
+		embed.Author.IconURL = actorIcon
+	}
+	setThumbnail(&embed, data, actorIcon)
+
+	kind := action
+	if event == "issue_comment" {
+		kind = "commented"
+	}
+	if emoji := eventEmojis[kind]; emoji != "" {
+		embed.Author.Name = emoji + " " + embed.Author.Name
+	}
+
+	issueKey := fmt.Sprintf("%v", data["id"])
+	if event == "issue_comment" || event == "issue_link" {
+		issueKey = fmt.Sprintf("%v", data["issue"])
+	}
+	if isDuplicate(issueKey, embed) {
+		log.Printf("[INFO] Skipping duplicate embed for issue %s", issueKey)
+		skip("duplicate")
+		return
+	}
+
+	targets := targetsFor(data)
+	if event == "issue_comment" || event == "issue_link" {
+		if issue, ok := data["issue_detail"].(map[string]interface{}); ok && isUnset(projectIDOf(data)) {
+			targets = targetsFor(issue)
+		}
+	}
+	var generic *genericEvent
+	if TargetPlatform == "generic" {
+		actorID, _ := actor["id"].(string)
+		ev := normalizeEvent(event, action, data, activities, genericActor{ID: actorID, Name: actorName}, embed)
+		generic, targets = &ev, GenericURLs
+	}
+
+	if holdForQuietHours(targets, embed, generic) {
+		log.Printf("[INFO] Quiet hours: holding event %s action: %s (mode: %s)", event, action, QuietHoursMode)
+		if QuietHoursMode == "digest" {
+			recordSent(issueKey, embed)
+			recordDelivery(delivery, time.Now())
+			respond(w, http.StatusOK, webhookResult{Status: "queued", Event: event, Action: action, Reason: "quiet hours"})
+		} else {
+			skip("quiet hours")
+		}
+		return
+	}
+
+	if event == "issue" && action == "created" && !coalesced {
+		markCreated(issueKey, time.Now())
+	}
+	ctx := deliveryContext{project: eventProjectName(data), issueKey: issueKey, action: action, coalesce: coalesced}
+	if event == "issue" && !coalesced && becameUrgent(data, action, activities) {
+		ctx.mention = UrgentMentionRoleID
+	}
+	if event == "issue" && EditInPlace && editableActions[action] {
+		current := issueStateEmbed(data, embed)
+		ctx.current = &current
+	}
+	if !dispatch(deliveryJob{targets: targets, embed: embed, ctx: ctx, generic: generic}) {
+		// 503 makes Plane retry the delivery later
+		switch {
+		case deliveryQueue == nil:
+			respond(w, http.StatusServiceUnavailable, webhookResult{Status: "rejected", Event: event, Action: action, Reason: "delivery failed"})
+		case QueueFullPolicy == "drop":
+			skip("queue full")
+		default:
+			respond(w, http.StatusServiceUnavailable, webhookResult{Status: "rejected", Event: event, Action: action, Reason: "queue full"})
+		}
+		return
+	}
+	recordSent(issueKey, embed)
+	recordDelivery(delivery, time.Now())
+	status := "forwarded"
+	if deliveryQueue != nil {
+		status = "queued"
+	}
+	respond(w, http.StatusOK, webhookResult{Status: status, Event: event, Action: action})
 }
 
 func main() {
+	if _, ok := signatureHashes[SignatureAlgo]; !ok {
+		log.Fatalf("[FATAL] Invalid SIGNATURE_ALGO %q: expected sha256 or sha1", SignatureAlgo)
+	}
+	if SkippedStatusCode < 200 || SkippedStatusCode > 599 {
+		log.Fatalf("[FATAL] Invalid SKIPPED_STATUS_CODE %d: expected an HTTP status between 200 and 599", SkippedStatusCode)
+	}
+	if WebhookSecret == "" {
+		if RequireSignature {
+			log.Fatalf("[FATAL] REQUIRE_SIGNATURE is set but WEBHOOK_SECRET is empty")
+		}
+		log.Println("[WARN] WEBHOOK_SECRET is not set: webhook signatures are not verified")
+	}
+	setupPlatform()
+	setupDedup()
+	setupLinks()
+	setupFilters()
+	setupTemplates()
+	setupQuietHours()
+	setupRouting()
+	loadState()
+	setupQueue()
+
 	// Health check endpoint for Dokploy/Traefik
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
+		if n := openCircuits(); n > 0 {
+			fmt.Fprintf(w, "DEGRADED: Discord circuit open for %d webhook(s)", n)
+			return
+		}
+		if n := disabledWebhookCount(); n > 0 {
+			fmt.Fprintf(w, "DEGRADED: %d Discord webhook(s) disabled", n)
+			return
+		}
 		w.Write([]byte("OK"))
 	})
 
-	http.HandleFunc("/", webhookHandler)
+	http.HandleFunc("/", limitInflight(webhookHandler))
+	registerAdminRoutes()
 
 	// Allow img directory for avatar URL
 	http.Handle("/img/", http.StripPrefix("/img/", http.FileServer(http.Dir("img"))))
 
+	// Timeouts keep slow clients from holding connections open indefinitely
+	srv := &http.Server{
+		Addr:              ":" + WebPort,
+		ReadHeaderTimeout: ReadTimeout,
+		ReadTimeout:       ReadTimeout,
+		WriteTimeout:      WriteTimeout,
+		IdleTimeout:       IdleTimeout,
+	}
+
+	if StartupNotification {
+		go notifyLifecycle("✅ Plane bridge online", colorRestored)
+	}
+
+	// Drain in-flight requests, queued deliveries and the quiet hours digest on
+	// SIGTERM so the offline notice goes out last
+	stopped := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		log.Printf("[INFO] Shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		drainQueue(ctx)
+		flushDigest()
+		flushState()
+		if ShutdownNotification {
+			notifyLifecycle("⏹️ Plane bridge offline", colorMuted)
+		}
+		close(stopped)
+	}()
+
 	log.Printf("[INFO] Server listening on port %s", WebPort)
-	log.Fatal(http.ListenAndServe(":"+WebPort, nil))
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-stopped
+}
+
+// notifyLifecycle posts a bridge status message to the default webhooks.
+func notifyLifecycle(title string, color int) {
+	embed := newEmbed()
+	embed.Title = title
+	embed.Color = color
+	embed.Description = fmt.Sprintf("Forwarding **%s** events to Discord.", WorkspaceName)
+	sendToDiscord(DiscordURLs, embed, deliveryContext{})
 }
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mockDiscord stands in for a Discord webhook and records every posted payload.
+type mockDiscord struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []map[string]json.RawMessage
+	methods  []string
+}
+
+func newMockDiscord(t *testing.T) *mockDiscord {
+	t.Helper()
+	m := &mockDiscord{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var p map[string]json.RawMessage
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("Discord received invalid JSON: %v", err)
+		}
+		m.mu.Lock()
+		m.payloads = append(m.payloads, p)
+		m.methods = append(m.methods, r.Method)
+		m.mu.Unlock()
+		if r.URL.Query().Get("wait") == "true" || r.Method == http.MethodPatch {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "1", "channel_id": "2"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(m.Close)
+
+	prev := DiscordURLs
+	DiscordURLs = []string{m.URL}
+	t.Cleanup(func() { DiscordURLs = prev })
+	return m
+}
+
+// embeds decodes the embeds of every payload received so far.
+func (m *mockDiscord) embeds(t *testing.T) []DiscordEmbed {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []DiscordEmbed
+	for _, p := range m.payloads {
+		var embeds []DiscordEmbed
+		if err := json.Unmarshal(p["embeds"], &embeds); err != nil {
+			t.Fatalf("decoding embeds: %v", err)
+		}
+		out = append(out, embeds...)
+	}
+	return out
+}
+
+// requests decodes every payload received so far, dropping the embed
+// timestamps since they default to the time of delivery.
+func (m *mockDiscord) requests(t *testing.T) []interface{} {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []interface{}
+	for _, p := range m.payloads {
+		raw, _ := json.Marshal(p)
+		var v map[string]interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			t.Fatalf("decoding payload: %v", err)
+		}
+		embeds, _ := v["embeds"].([]interface{})
+		for _, e := range embeds {
+			delete(e.(map[string]interface{}), "timestamp")
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+func postEvent(t *testing.T, payload map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	rec := httptest.NewRecorder()
+	webhookHandler(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body))))
+	return rec
+}
+
+func TestWebhookHandlerDeliversEmbeds(t *testing.T) {
+	alice := map[string]interface{}{"id": "u-alice", "display_name": "Alice"}
+	project := map[string]interface{}{"name": "Backend", "identifier": "ENG"}
+
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+		want    string // the request body Discord receives, without the embed timestamp
+	}{
+		{
+			name: "created",
+			payload: map[string]interface{}{
+				"event": "issue", "action": "created",
+				"data": map[string]interface{}{
+					"id": "issue-created", "name": "Login fails", "priority": "high",
+					"sequence_id": 7, "project_detail": project,
+				},
+				"activity": map[string]interface{}{"actor": alice},
+			},
+			want: `{
+				"username": "Plane",
+				"avatar_url": "https://plane.so/img/plane-icon.png",
+				"embeds": [{
+					"title": "Login fails",
+					"url": "https://plane.so/workspace/browse/ENG-7/",
+					"color": 8184715,
+					"author": {"name": "🆕 Alice created an issue", "icon_url": "https://plane.so/img/plane-icon.png"},
+					"footer": {"text": "Plane Bridge", "icon_url": ""},
+					"fields": [{"name": "Priority", "value": "🟠 High", "inline": true}]
+				}]
+			}`,
+		},
+		{
+			name: "updated",
+			payload: map[string]interface{}{
+				"event": "issue", "action": "updated",
+				"data": map[string]interface{}{
+					"id": "issue-updated", "name": "Login fails", "priority": "urgent", "project_detail": project,
+				},
+				"activity": map[string]interface{}{
+					"actor": alice, "field": "priority", "old_value": "low", "new_value": "urgent",
+				},
+			},
+			want: `{
+				"username": "Plane",
+				"avatar_url": "https://plane.so/img/plane-icon.png",
+				"embeds": [{
+					"title": "Login fails",
+					"description": "Field **priority** changed.",
+					"color": 4093438,
+					"author": {"name": "✏️ Alice", "icon_url": "https://plane.so/img/plane-icon.png"},
+					"footer": {"text": "Plane Bridge", "icon_url": ""},
+					"fields": [{"name": "Change", "value": "` + "`🔵 Low` → `🔴 Urgent!`" + `", "inline": false}]
+				}]
+			}`,
+		},
+		{
+			name: "deleted",
+			payload: map[string]interface{}{
+				"event": "issue", "action": "deleted",
+				"data":     map[string]interface{}{"id": "issue-deleted"},
+				"activity": map[string]interface{}{"actor": alice},
+			},
+			want: `{
+				"username": "Plane",
+				"avatar_url": "https://plane.so/img/plane-icon.png",
+				"embeds": [{
+					"description": "ID: ` + "`issue-deleted`" + `",
+					"color": 16415088,
+					"author": {"name": "🗑️ Alice deleted an issue", "icon_url": "https://plane.so/img/plane-icon.png"},
+					"footer": {"text": "Plane Bridge", "icon_url": ""}
+				}]
+			}`,
+		},
+		{
+			name: "comment",
+			payload: map[string]interface{}{
+				"event": "issue_comment", "action": "created",
+				"data": map[string]interface{}{
+					"id": "comment-1", "issue": "issue-commented", "comment_stripped": "Looks good to me",
+					"issue_detail": map[string]interface{}{"name": "Login fails"},
+				},
+				"activity": map[string]interface{}{"actor": alice},
+			},
+			want: `{
+				"username": "Plane",
+				"avatar_url": "https://plane.so/img/plane-icon.png",
+				"embeds": [{
+					"title": "Login fails",
+					"description": "Looks good to me",
+					"color": 8184715,
+					"author": {"name": "💬 Alice commented", "icon_url": "https://plane.so/img/plane-icon.png"},
+					"footer": {"text": "Plane Bridge", "icon_url": ""},
+					"fields": [{"name": "Issue ID", "value": "issue-commented", "inline": true}]
+				}]
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discord := newMockDiscord(t)
+			rec := postEvent(t, tt.payload)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+			}
+			got := discord.requests(t)
+			if len(got) != 1 {
+				t.Fatalf("Discord received %d requests, want 1", len(got))
+			}
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("decoding expected payload: %v", err)
+			}
+			if !reflect.DeepEqual(got[0], want) {
+				gotJSON, _ := json.MarshalIndent(got[0], "", "  ")
+				t.Errorf("Discord received:\n%s", gotJSON)
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerSkipsUntrackedField(t *testing.T) {
+	discord := newMockDiscord(t)
+	rec := postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "updated",
+		"data":     map[string]interface{}{"id": "issue-untracked", "name": "Login fails"},
+		"activity": map[string]interface{}{"field": "description_html", "old_value": "a", "new_value": "b"},
+	})
+	if !strings.Contains(rec.Body.String(), "field not tracked") {
+		t.Errorf("body = %s", rec.Body)
+	}
+	if n := len(discord.embeds(t)); n != 0 {
+		t.Errorf("Discord received %d embeds, want 0", n)
+	}
+}
+
+func TestDebounced(t *testing.T) {
+	base := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name       string
+		first, key string
+		after      time.Duration
+		want       bool
+	}{
+		{"same second", "a", "a", 0, true},
+		{"just inside 2s", "b", "b", 1999 * time.Millisecond, true},
+		{"just outside 2s", "c", "c", 2 * time.Second, false},
+		{"different key", "d", "e", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			lastUpdated = make(map[string]int64)
+			mu.Unlock()
+
+			if debounced(tt.first, base) {
+				t.Fatalf("first update of %q was debounced", tt.first)
+			}
+			if got := debounced(tt.key, base.Add(tt.after)); got != tt.want {
+				t.Errorf("debounced(%q, +%s) = %t, want %t", tt.key, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureUsesRawBody(t *testing.T) {
+	prev := WebhookSecret
+	WebhookSecret = "s3cret"
+	t.Cleanup(func() { WebhookSecret = prev })
+
+	// Key order and spacing as Plane sends them, which re-marshalling does not preserve
+	raw := []byte(`{"event": "issue", "action": "created", "data": {"name": "Login fails"}}`)
+	sig := computeSignature(WebhookSecret, raw)
+	if !verifySignature(raw, sig) {
+		t.Fatal("raw body did not verify")
+	}
+
+	var p map[string]interface{}
+	json.Unmarshal(raw, &p)
+	remarshalled, _ := json.Marshal(p)
+	if verifySignature(remarshalled, sig) {
+		t.Error("re-marshalled body verified against the raw body's signature")
+	}
+}
+
+func TestPriorityLabelMixedCase(t *testing.T) {
+	tests := map[string]string{
+		"Urgent":  "🔴 Urgent!",
+		" HIGH ":  "🟠 High",
+		"unknown": "⚫ None",
+	}
+	for in, want := range tests {
+		if got := priorityLabel(in); got != want {
+			t.Errorf("priorityLabel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAvatarCheckedOnlyForDeliveredEvents(t *testing.T) {
+	var heads atomic.Int32
+	avatars := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		heads.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+	}))
+	defer avatars.Close()
+	prev := CheckAvatarURLs
+	CheckAvatarURLs = true
+	t.Cleanup(func() { CheckAvatarURLs = prev })
+
+	discord := newMockDiscord(t)
+	actor := map[string]interface{}{"id": "u-bob", "display_name": "Bob", "avatar_url": avatars.URL + "/bob.png"}
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "updated",
+		"data":     map[string]interface{}{"id": "issue-avatar-skipped", "name": "Login fails"},
+		"activity": map[string]interface{}{"actor": actor, "field": "description_html", "old_value": "a", "new_value": "b"},
+	})
+	if n := heads.Load(); n != 0 {
+		t.Errorf("skipped event checked the avatar %d times", n)
+	}
+
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "created",
+		"data":     map[string]interface{}{"id": "issue-avatar-delivered", "name": "Login fails"},
+		"activity": map[string]interface{}{"actor": actor},
+	})
+	if n := heads.Load(); n != 1 {
+		t.Errorf("delivered event checked the avatar %d times, want 1", n)
+	}
+	if embeds := discord.embeds(t); len(embeds) != 1 || embeds[0].Author.IconURL != avatars.URL+"/bob.png" {
+		t.Errorf("embeds = %+v", embeds)
+	}
+}
+
+func TestAvatarChecksCapped(t *testing.T) {
+	avatarChecksMu.Lock()
+	avatarChecks = map[string]avatarCheck{}
+	for i := 0; i < maxAvatarChecks; i++ {
+		avatarChecks[strings.Repeat("x", i)] = avatarCheck{at: time.Now()}
+	}
+	avatarChecksMu.Unlock()
+
+	prev := CheckAvatarURLs
+	CheckAvatarURLs = true
+	t.Cleanup(func() { CheckAvatarURLs = prev })
+	avatarReachable("http://127.0.0.1:1/avatar.png")
+
+	avatarChecksMu.Lock()
+	defer avatarChecksMu.Unlock()
+	if n := len(avatarChecks); n > maxAvatarChecks {
+		t.Errorf("avatar cache holds %d entries, want at most %d", n, maxAvatarChecks)
+	}
+}
+
+func TestIsSystemActor(t *testing.T) {
+	prev := SystemActors
+	SystemActors = []string{"u-ci"}
+	t.Cleanup(func() { SystemActors = prev })
+
+	tests := []struct {
+		name  string
+		actor map[string]interface{}
+		want  bool
+	}{
+		{"bot flag", map[string]interface{}{"id": "u-bot", "display_name": "Importer", "is_bot": true}, true},
+		{"listed", map[string]interface{}{"id": "u-ci", "display_name": "CI"}, true},
+		{"person", map[string]interface{}{"id": "u-alice", "display_name": "Alice"}, false},
+		{"no name", map[string]interface{}{"id": "u-anon"}, false},
+		{"no actor", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isSystemActor(tt.actor); got != tt.want {
+			t.Errorf("%s: isSystemActor = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+// content decodes the plain text content of every payload received so far.
+func (m *mockDiscord) content(t *testing.T) []string {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []string
+	for _, p := range m.payloads {
+		var s string
+		json.Unmarshal(p["content"], &s)
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestMessageStylesKeepMergedChanges(t *testing.T) {
+	payload := map[string]interface{}{
+		"event": "issue", "action": "updated",
+		"data": map[string]interface{}{"id": "issue-merged", "name": "Login fails", "priority": "urgent", "estimate_point": "5"},
+		"activity": []interface{}{
+			map[string]interface{}{"field": "priority", "old_value": "low", "new_value": "urgent"},
+			map[string]interface{}{"field": "estimate_point", "old_value": "3", "new_value": "5"},
+		},
+	}
+	prev := MessageStyle
+	t.Cleanup(func() { MessageStyle = prev })
+
+	t.Run("compact", func(t *testing.T) {
+		MessageStyle = "compact"
+		discord := newMockDiscord(t)
+		payload["data"].(map[string]interface{})["id"] = "issue-merged-compact"
+		postEvent(t, payload)
+		embeds := discord.embeds(t)
+		if len(embeds) != 1 || len(embeds[0].Fields) != 2 {
+			t.Fatalf("embeds = %+v", embeds)
+		}
+		if f := embeds[0].Fields[0]; f.Name != "priority" || f.Value != "`🔵 Low` → `🔴 Urgent!`" {
+			t.Errorf("first field = %+v", f)
+		}
+	})
+	t.Run("plain", func(t *testing.T) {
+		MessageStyle = "plain"
+		discord := newMockDiscord(t)
+		payload["data"].(map[string]interface{})["id"] = "issue-merged-plain"
+		postEvent(t, payload)
+		content := discord.content(t)
+		if len(content) != 1 || !strings.Contains(content[0], "priority: `🔵 Low` → `🔴 Urgent!`") {
+			t.Errorf("content = %q", content)
+		}
+	})
+}
+
+func TestMessageStylesKeepRenamesAndAssigneeDiffs(t *testing.T) {
+	prevStyle, prevMap := MessageStyle, UserMap
+	UserMap = map[string]string{"u-bob": "Bob"}
+	t.Cleanup(func() { MessageStyle, UserMap = prevStyle, prevMap })
+
+	rename := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"event": "issue", "action": "updated",
+			"data":     map[string]interface{}{"id": id, "name": "Login fails on Safari"},
+			"activity": map[string]interface{}{"field": "name", "old_value": "Login fails", "new_value": "Login fails on Safari"},
+		}
+	}
+	reassign := func(id string) map[string]interface{} {
+		return map[string]interface{}{
+			"event": "issue", "action": "updated",
+			"data": map[string]interface{}{
+				"id": id, "name": "Login fails", "assignee_ids": []interface{}{"u-alice"},
+				"assignees": []interface{}{map[string]interface{}{"id": "u-alice", "display_name": "Alice"}},
+			},
+			"activity": map[string]interface{}{"field": "assignee_ids", "old_value": []interface{}{"u-bob"}, "new_value": []interface{}{"u-alice"}},
+		}
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		MessageStyle = "plain"
+		discord := newMockDiscord(t)
+		postEvent(t, rename("issue-rename-plain"))
+		postEvent(t, reassign("issue-reassign-plain"))
+		content := discord.content(t)
+		if len(content) != 2 {
+			t.Fatalf("content = %q", content)
+		}
+		if !strings.Contains(content[0], "From: Login fails · To: Login fails on Safari") {
+			t.Errorf("rename = %q", content[0])
+		}
+		if !strings.Contains(content[1], "Alice** was assigned") || !strings.Contains(content[1], "Bob** was unassigned") {
+			t.Errorf("assignee diff = %q", content[1])
+		}
+	})
+	t.Run("compact", func(t *testing.T) {
+		MessageStyle = "compact"
+		discord := newMockDiscord(t)
+		postEvent(t, rename("issue-rename-compact"))
+		postEvent(t, reassign("issue-reassign-compact"))
+		embeds := discord.embeds(t)
+		if len(embeds) != 2 {
+			t.Fatalf("embeds = %+v", embeds)
+		}
+		if len(embeds[0].Fields) != 2 || embeds[0].Fields[1].Value != "Login fails on Safari" {
+			t.Errorf("rename fields = %+v", embeds[0].Fields)
+		}
+		if !strings.Contains(embeds[1].Description, "Bob** was unassigned") {
+			t.Errorf("assignee diff = %q", embeds[1].Description)
+		}
+	})
+}
+
+func TestForumThreadSharedByIssueAndComment(t *testing.T) {
+	prev := ForumThreadsByProject
+	ForumThreadsByProject = true
+	t.Cleanup(func() { ForumThreadsByProject = prev })
+	mockPlane(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name": "Forum Backend"}`))
+	})
+	discord := newMockDiscord(t)
+
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "created",
+		"data": map[string]interface{}{
+			"id": "issue-forum", "name": "Login fails", "project": "p-forum",
+			"project_detail": map[string]interface{}{"name": "Forum Backend"},
+		},
+	})
+	postEvent(t, map[string]interface{}{
+		"event": "issue_comment", "action": "created",
+		"data": map[string]interface{}{
+			"id": "comment-forum", "issue": "issue-forum", "project": "p-forum", "comment_stripped": "On it",
+		},
+	})
+
+	discord.mu.Lock()
+	defer discord.mu.Unlock()
+	if len(discord.payloads) != 2 {
+		t.Fatalf("Discord received %d payloads, want 2", len(discord.payloads))
+	}
+	threads := 0
+	for _, p := range discord.payloads {
+		if _, ok := p["thread_name"]; ok {
+			threads++
+		}
+	}
+	if threads != 1 {
+		t.Errorf("opened %d forum threads, want 1", threads)
+	}
+}
+
+func TestEditInPlaceShowsCurrentState(t *testing.T) {
+	prev := EditInPlace
+	EditInPlace = true
+	t.Cleanup(func() { EditInPlace = prev })
+	discord := newMockDiscord(t)
+
+	issue := map[string]interface{}{
+		"id": "issue-edited", "name": "Login fails", "priority": "low",
+		"state": map[string]interface{}{"name": "Todo"}, "description_stripped": "Safari only",
+	}
+	postEvent(t, map[string]interface{}{"event": "issue", "action": "created", "data": issue})
+	issue["priority"] = "urgent"
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "updated", "data": issue,
+		"activity": map[string]interface{}{"field": "priority", "old_value": "low", "new_value": "urgent"},
+	})
+	// Forget the first update so the debounce lets the second through
+	mu.Lock()
+	delete(lastUpdated, "issue-edited")
+	mu.Unlock()
+	issue["state"] = map[string]interface{}{"name": "In Progress"}
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "updated", "data": issue,
+		"activity": map[string]interface{}{"field": "state", "old_value": "Todo", "new_value": "In Progress"},
+	})
+
+	embeds := discord.embeds(t)
+	if fmt.Sprint(discord.methods) != "[POST PATCH PATCH]" || len(embeds) != 3 {
+		t.Fatalf("Discord received %v with %d embeds", discord.methods, len(embeds))
+	}
+	last := embeds[2]
+	if last.Description != "Safari only" {
+		t.Errorf("description = %q, want the issue's", last.Description)
+	}
+	want := []EmbedField{
+		{Name: "State", Value: "In Progress", Inline: true},
+		{Name: "Priority", Value: "🔴 Urgent!", Inline: true},
+	}
+	if !reflect.DeepEqual(last.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", last.Fields, want)
+	}
+}
+
+func TestRequiredLabelsKeepDeletions(t *testing.T) {
+	prev := RequiredLabels
+	RequiredLabels = []string{"backend"}
+	t.Cleanup(func() { RequiredLabels = prev })
+	discord := newMockDiscord(t)
+
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "created",
+		"data": map[string]interface{}{"id": "issue-unlabelled", "name": "Login fails"},
+	})
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "deleted",
+		"data": map[string]interface{}{"id": "issue-labelled-deleted"},
+	})
+	embeds := discord.embeds(t)
+	if len(embeds) != 1 || embeds[0].Description != "ID: `issue-labelled-deleted`" {
+		t.Errorf("embeds = %+v, want only the deletion", embeds)
+	}
+}
+
+func TestCreatedPastDue(t *testing.T) {
+	discord := newMockDiscord(t)
+	postEvent(t, map[string]interface{}{
+		"event": "issue", "action": "created",
+		"data": map[string]interface{}{"id": "issue-imported", "name": "Old task", "target_date": "2020-01-31"},
+	})
+	embeds := discord.embeds(t)
+	if len(embeds) != 1 {
+		t.Fatalf("Discord received %d embeds, want 1", len(embeds))
+	}
+	if embeds[0].Color != colorWarning || embeds[0].Description != "⚠️ **Past due**" {
+		t.Errorf("color = %d, description = %q", embeds[0].Color, embeds[0].Description)
+	}
+}
+
+func TestRelationChangeRemovedWithUpdatedVerb(t *testing.T) {
+	data := map[string]interface{}{
+		"sequence_id": float64(9), "project_detail": map[string]interface{}{"identifier": "ENG"},
+	}
+	activity := map[string]interface{}{"verb": "updated"}
+	got := relationChange(data, activity, relationVerbs["blocking"], "ENG-3", "")
+	if want := "🔗 **ENG-9** no longer blocks **ENG-3**"; got != want {
+		t.Errorf("relationChange = %q, want %q", got, want)
+	}
+}
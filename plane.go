@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Plane API ---
+var (
+	PlaneAPIURL   = getEnv("PLANE_API_URL", AppURL)
+	PlaneAPIToken = getEnv("PLANE_API_TOKEN", "")
+
+	// Static fallback for resolving user IDs, e.g. "uuid=Alice,uuid2=Bob"
+	UserMap = getEnvMap("USER_MAP")
+
+	// How @mention tokens in comments are shown: "resolve" (display names where
+	// known, the raw token otherwise), "strip" or "keep"
+	MentionMode = strings.ToLower(getEnv("MENTION_MODE", "resolve"))
+)
+
+// Mention tokens as they appear in Plane comment text: the editor's
+// mention-component tag, or a bare @ followed by a user ID
+var mentionPattern = regexp.MustCompile(`<mention-component[^>]*entity_identifier="([0-9a-fA-F-]{36})"[^>]*>(?:</mention-component>)?|@\[?([0-9a-fA-F]{8}-[0-9a-fA-F-]{27})\]?`)
+
+var planeClient = &http.Client{Timeout: 5 * time.Second}
+
+// Workspace members cached from the Plane API, keyed by user ID
+var (
+	members        = map[string]string{}
+	membersFetched time.Time
+	membersMu      sync.Mutex
+)
+
+// Project names looked up by ID, for activities that only carry project IDs.
+// projectFetched throttles lookups of IDs not resolved yet, failures included.
+var (
+	projectNames   = map[string]string{}
+	projectFetched = map[string]time.Time{}
+	projectNamesMu sync.Mutex
+)
+
+// planeGet fetches a Plane API path into v. It is a no-op without PLANE_API_TOKEN.
+func planeGet(path string, v interface{}) error {
+	if PlaneAPIToken == "" {
+		return fmt.Errorf("PLANE_API_TOKEN not set")
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(PlaneAPIURL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", PlaneAPIToken)
+	resp, err := planeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// refreshMembers fetches the member list and swaps it into the cache. It runs
+// without membersMu held so lookups aren't blocked on the Plane API.
+func refreshMembers() {
+	var list []map[string]interface{}
+	if err := planeGet(fmt.Sprintf("/api/v1/workspaces/%s/members/", WorkspaceSlug), &list); err != nil {
+		log.Printf("[WARN] Fetching workspace members: %v", err)
+		return
+	}
+	fetched := make(map[string]string, len(list))
+	for _, m := range list {
+		// Some versions nest the user under "member"
+		if inner, ok := m["member"].(map[string]interface{}); ok {
+			m = inner
+		}
+		if id, ok := m["id"].(string); ok {
+			fetched[strings.ToLower(id)] = actorDisplayName(m)
+		}
+	}
+	membersMu.Lock()
+	members = fetched
+	membersMu.Unlock()
+}
+
+// userName resolves a user ID via USER_MAP or the cached member list. The
+// cache refreshes at most once a minute on a miss, failed fetches included,
+// and only one caller fetches while the others see the current cache.
+func userName(id string) (string, bool) {
+	id = strings.ToLower(id)
+	if name, ok := UserMap[id]; ok {
+		return name, true
+	}
+	if PlaneAPIToken == "" {
+		return "", false
+	}
+
+	membersMu.Lock()
+	name, ok := members[id]
+	refresh := !(ok && name != "") && time.Since(membersFetched) > time.Minute
+	if refresh {
+		membersFetched = time.Now()
+	}
+	membersMu.Unlock()
+	if !refresh {
+		return name, ok && name != ""
+	}
+	refreshMembers()
+	return cachedUserName(id)
+}
+
+// cleanMentions rewrites @mention tokens in comment text according to MENTION_MODE.
+func cleanMentions(text string) string {
+	if MentionMode == "keep" {
+		return text
+	}
+	return mentionPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if MentionMode == "strip" {
+			return ""
+		}
+		m := mentionPattern.FindStringSubmatch(token)
+		id := m[1] + m[2]
+		if name, ok := cachedUserName(id); ok {
+			return "@" + name
+		}
+		// Unresolved tokens are kept so no information is lost; the lookup
+		// warms the member cache in the background instead of blocking here
+		go userName(id)
+		return token
+	})
+}
+
+// cachedUserName resolves a user ID from USER_MAP or the member cache without
+// calling the Plane API.
+func cachedUserName(id string) (string, bool) {
+	id = strings.ToLower(id)
+	if name, ok := UserMap[id]; ok {
+		return name, true
+	}
+	membersMu.Lock()
+	defer membersMu.Unlock()
+	name, ok := members[id]
+	return name, ok && name != ""
+}
+
+// projectName resolves a project ID to its name through the Plane API. Like
+// userName it fetches without the lock held, at most once a minute per ID.
+func projectName(id string) (string, bool) {
+	if isUnset(id) || PlaneAPIToken == "" {
+		return "", false
+	}
+	projectNamesMu.Lock()
+	if name, ok := projectNames[id]; ok {
+		projectNamesMu.Unlock()
+		return name, name != ""
+	}
+	if time.Since(projectFetched[id]) <= time.Minute {
+		projectNamesMu.Unlock()
+		return "", false
+	}
+	projectFetched[id] = time.Now()
+	projectNamesMu.Unlock()
+
+	var project struct {
+		Name string `json:"name"`
+	}
+	if err := planeGet(fmt.Sprintf("/api/v1/workspaces/%s/projects/%s/", WorkspaceSlug, id), &project); err != nil {
+		log.Printf("[WARN] Fetching project %s: %v", id, err)
+		return "", false
+	}
+	projectNamesMu.Lock()
+	projectNames[id] = project.Name
+	delete(projectFetched, id)
+	projectNamesMu.Unlock()
+	return project.Name, project.Name != ""
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mockPlane serves the Plane API paths the bridge reads, counting requests.
+func mockPlane(t *testing.T, handler http.HandlerFunc) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	prevURL, prevToken := PlaneAPIURL, PlaneAPIToken
+	PlaneAPIURL, PlaneAPIToken = srv.URL, "token"
+	t.Cleanup(func() { PlaneAPIURL, PlaneAPIToken = prevURL, prevToken })
+	return &calls
+}
+
+func TestUserNameFetchesOnce(t *testing.T) {
+	release := make(chan struct{})
+	calls := mockPlane(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`[{"id": "0b1c2d3e-0000-0000-0000-000000000001", "display_name": "Alice"}]`))
+	})
+	membersMu.Lock()
+	members, membersFetched = map[string]string{}, time.Time{}
+	membersMu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			userName("0b1c2d3e-0000-0000-0000-000000000001")
+		}()
+	}
+	// Cache reads must not wait on the fetch in flight
+	done := make(chan struct{})
+	go func() {
+		cachedUserName("unknown")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cachedUserName blocked on the member fetch")
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetched members %d times, want 1", n)
+	}
+	if name, ok := cachedUserName("0b1c2d3e-0000-0000-0000-000000000001"); !ok || name != "Alice" {
+		t.Errorf("cachedUserName = %q, %t", name, ok)
+	}
+}
+
+func TestUserNameCachesFailure(t *testing.T) {
+	calls := mockPlane(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	membersMu.Lock()
+	members, membersFetched = map[string]string{}, time.Time{}
+	membersMu.Unlock()
+
+	userName("0b1c2d3e-0000-0000-0000-000000000002")
+	userName("0b1c2d3e-0000-0000-0000-000000000002")
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetched members %d times after a failure, want 1", n)
+	}
+}
+
+func TestProjectNameCachesFailure(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	calls := mockPlane(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"name": "Backend"}`))
+	})
+
+	if _, ok := projectName("project-failing"); ok {
+		t.Fatal("failed lookup resolved")
+	}
+	fail.Store(false)
+	if _, ok := projectName("project-failing"); ok || calls.Load() != 1 {
+		t.Errorf("failed lookup retried within a minute (%d calls)", calls.Load())
+	}
+
+	projectNamesMu.Lock()
+	projectFetched["project-failing"] = time.Now().Add(-2 * time.Minute)
+	projectNamesMu.Unlock()
+	if name, ok := projectName("project-failing"); !ok || name != "Backend" {
+		t.Errorf("projectName after backoff = %q, %t", name, ok)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Delivery Queue ---
+// With DELIVERY_QUEUE_SIZE > 0 the handler acknowledges Plane immediately and
+// workers deliver in the background. 0 keeps delivery synchronous.
+var (
+	DeliveryQueueSize = getEnvInt("DELIVERY_QUEUE_SIZE", 0)
+	DeliveryWorkers   = getEnvInt("DELIVERY_WORKERS", 2)
+	QueueFullPolicy   = getEnv("QUEUE_FULL_POLICY", "drop") // "drop", "block" or "reject"
+	QueueFullTimeout  = getEnvDuration("QUEUE_FULL_TIMEOUT", 5*time.Second)
+
+	// Workers wait BATCH_WINDOW for more jobs and post up to 10 embeds per message
+	BatchEmbeds = getEnvBool("BATCH_EMBEDS", false)
+	BatchWindow = getEnvDuration("BATCH_WINDOW", 500*time.Millisecond)
+)
+
+type deliveryJob struct {
+	targets []string
+	embed   DiscordEmbed
+	ctx     deliveryContext
+	generic *genericEvent // set under TARGET_PLATFORM=generic; targets are then GENERIC_WEBHOOK_URLs
+}
+
+// send delivers a single job, reporting false only when a generic event
+// reached none of its endpoints.
+func (job deliveryJob) send() bool {
+	if job.generic != nil {
+		return sendGeneric(job.targets, *job.generic)
+	}
+	sendToDiscord(job.targets, job.embed, job.ctx)
+	return true
+}
+
+var (
+	deliveryQueue chan deliveryJob
+	queuedJobs    sync.WaitGroup // accepted jobs not yet delivered, waited on at shutdown
+	metricDropped = expvar.NewInt("delivery_queue_dropped")
+)
+
+func setupQueue() {
+	if DeliveryQueueSize <= 0 {
+		return
+	}
+	switch QueueFullPolicy {
+	case "drop", "block", "reject":
+	default:
+		log.Fatalf("[FATAL] Invalid QUEUE_FULL_POLICY %q: expected drop, block or reject", QueueFullPolicy)
+	}
+	deliveryQueue = make(chan deliveryJob, DeliveryQueueSize)
+	expvar.Publish("delivery_queue_length", expvar.Func(func() interface{} {
+		return len(deliveryQueue)
+	}))
+	// Batched messages hold several issues, so they can't be tracked per issue
+	if BatchEmbeds && (EditInPlace || ReplyToCreated || DiscordWait || CoalesceCreateWindow > 0) {
+		log.Printf("[WARN] BATCH_EMBEDS is ignored while message tracking (EDIT_IN_PLACE, REPLY_TO_CREATED, DISCORD_WAIT, COALESCE_CREATE_WINDOW) is enabled")
+		BatchEmbeds = false
+	}
+	for i := 0; i < max(DeliveryWorkers, 1); i++ {
+		go worker()
+	}
+	log.Printf("[INFO] Async delivery: queue %d, workers %d, when full: %s", DeliveryQueueSize, DeliveryWorkers, QueueFullPolicy)
+}
+
+func worker() {
+	for job := range deliveryQueue {
+		if !BatchEmbeds || job.generic != nil {
+			job.send()
+			queuedJobs.Done()
+			continue
+		}
+		batch := []deliveryJob{job}
+		timer := time.NewTimer(BatchWindow)
+	collect:
+		for len(batch) < maxBatchEmbeds {
+			select {
+			case next := <-deliveryQueue:
+				batch = append(batch, next)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		sendBatch(batch)
+		for range batch {
+			queuedJobs.Done()
+		}
+	}
+}
+
+// drainQueue waits until queued jobs are delivered or ctx expires.
+func drainQueue(ctx context.Context) {
+	if deliveryQueue == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		queuedJobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("[WARN] Shutting down with %d deliveries still queued", len(deliveryQueue))
+	}
+}
+
+// sendBatch groups jobs by destination and posts them in messages of up to
+// maxBatchEmbeds embeds, keeping each message under Discord's size limit.
+func sendBatch(batch []deliveryJob) {
+	var order []string
+	groups := make(map[string][]deliveryJob)
+	for _, job := range batch {
+		if job.generic != nil {
+			job.send()
+			continue
+		}
+		key := strings.Join(job.targets, ",") + "|" + job.ctx.project + "|" + job.ctx.mention
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], job)
+	}
+
+	for _, key := range order {
+		jobs := groups[key]
+		var embeds []DiscordEmbed
+		chars := 0
+		for _, job := range jobs {
+			size := embedChars(job.embed)
+			if len(embeds) == maxBatchEmbeds || (len(embeds) > 0 && chars+size > maxBatchChars) {
+				sendEmbeds(jobs[0].targets, embeds, jobs[0].ctx)
+				embeds, chars = nil, 0
+			}
+			embeds = append(embeds, job.embed)
+			chars += size
+		}
+		sendEmbeds(jobs[0].targets, embeds, jobs[0].ctx)
+	}
+}
+
+// dispatch delivers the job now or queues it. It returns false when the
+// queue is full and the job was not accepted under QUEUE_FULL_POLICY, or when
+// synchronous generic delivery failed at every endpoint.
+func dispatch(job deliveryJob) bool {
+	if deliveryQueue == nil {
+		return job.send()
+	}
+
+	queuedJobs.Add(1)
+	select {
+	case deliveryQueue <- job:
+		return true
+	default:
+	}
+
+	if QueueFullPolicy == "block" {
+		timer := time.NewTimer(QueueFullTimeout)
+		defer timer.Stop()
+		select {
+		case deliveryQueue <- job:
+			return true
+		case <-timer.C:
+		}
+	}
+	queuedJobs.Done()
+	metricDropped.Add(1)
+	log.Printf("[WARN] Delivery queue full (%d), policy: %s", DeliveryQueueSize, QueueFullPolicy)
+	return false
+}
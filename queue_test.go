@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrainQueueDeliversQueuedJobs(t *testing.T) {
+	discord := newMockDiscord(t)
+	prev := deliveryQueue
+	deliveryQueue = make(chan deliveryJob, 10)
+	t.Cleanup(func() { deliveryQueue = prev })
+	go worker()
+
+	for i := 0; i < 3; i++ {
+		embed := newEmbed()
+		embed.Title = "Queued"
+		if !dispatch(deliveryJob{targets: DiscordURLs, embed: embed}) {
+			t.Fatal("job not accepted")
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	drainQueue(ctx)
+	if n := len(discord.embeds(t)); n != 3 {
+		t.Errorf("Discord received %d embeds after draining, want 3", n)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --- Quiet Hours ---
+var (
+	QuietHours     = getEnv("QUIET_HOURS", "")          // e.g. "22:00-07:00"
+	QuietHoursMode = getEnv("QUIET_HOURS_MODE", "drop") // "drop" or "digest"
+	Timezone       = getEnv("TIMEZONE", "UTC")
+)
+
+// quietWindow is a daily time range in minutes since midnight. It may wrap past midnight.
+type quietWindow struct {
+	start, end int
+}
+
+var (
+	quiet    *quietWindow
+	location = time.UTC
+
+	// Embeds held back during quiet hours, flushed as one digest per target set afterwards
+	digest   []heldEmbed
+	digestMu sync.Mutex
+)
+
+type heldEmbed struct {
+	targets []string
+	embed   DiscordEmbed
+	generic *genericEvent // set under TARGET_PLATFORM=generic
+}
+
+func setupQuietHours() {
+	loc, err := time.LoadLocation(Timezone)
+	if err != nil {
+		log.Fatalf("[FATAL] Invalid TIMEZONE %q: %v", Timezone, err)
+	}
+	location = loc
+
+	if QuietHours == "" {
+		return
+	}
+	q, err := parseQuietHours(QuietHours)
+	if err != nil {
+		log.Fatalf("[FATAL] Invalid QUIET_HOURS %q: %v", QuietHours, err)
+	}
+	if QuietHoursMode != "drop" && QuietHoursMode != "digest" {
+		log.Fatalf("[FATAL] Invalid QUIET_HOURS_MODE %q: expected drop or digest", QuietHoursMode)
+	}
+	quiet = &q
+	if QuietHoursMode == "digest" {
+		go digestLoop()
+	}
+	log.Printf("[INFO] Quiet hours %s (%s), mode: %s", QuietHours, Timezone, QuietHoursMode)
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseQuietHours(s string) (quietWindow, error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return quietWindow{}, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return quietWindow{}, err
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return quietWindow{}, err
+	}
+	return quietWindow{start: start, end: end}, nil
+}
+
+func (q quietWindow) contains(t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if q.start <= q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+func isQuiet(t time.Time) bool {
+	return quiet != nil && quiet.contains(t.In(location))
+}
+
+// holdForQuietHours reports whether the embed must not be sent right now,
+// queueing it for the digest when that mode is enabled.
+func holdForQuietHours(targets []string, embed DiscordEmbed, generic *genericEvent) bool {
+	if !isQuiet(time.Now()) {
+		return false
+	}
+	if QuietHoursMode == "digest" {
+		digestMu.Lock()
+		digest = append(digest, heldEmbed{targets: targets, embed: embed, generic: generic})
+		digestMu.Unlock()
+	}
+	return true
+}
+
+func digestLoop() {
+	for range time.Tick(time.Minute) {
+		if !isQuiet(time.Now()) {
+			flushDigest()
+		}
+	}
+}
+
+func flushDigest() {
+	digestMu.Lock()
+	queued := digest
+	digest = nil
+	digestMu.Unlock()
+
+	// Group by destination so routed projects get their own digest
+	var order []string
+	groups := make(map[string][]heldEmbed)
+	for _, h := range queued {
+		// Generic endpoints get the held events themselves, not a Discord digest
+		if h.generic != nil {
+			sendGeneric(h.targets, *h.generic)
+			continue
+		}
+		key := strings.Join(h.targets, ",")
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], h)
+	}
+	for _, key := range order {
+		held := groups[key]
+		embeds := make([]DiscordEmbed, len(held))
+		for i, h := range held {
+			embeds[i] = h.embed
+		}
+		sendDigest(held[0].targets, embeds)
+	}
+}
+
+// Each digest entry is a one-line summary of the held event
+const (
+	maxDigestName  = 100
+	maxDigestValue = 200
+)
+
+// sendDigest posts the held events as summary fields, splitting them across
+// messages so each stays under Discord's size limit.
+func sendDigest(targets []string, queued []DiscordEmbed) {
+	var fields []EmbedField
+	for _, e := range queued {
+		name := WorkspaceName
+		if e.Author != nil && e.Author.Name != "" {
+			name = e.Author.Name
+		}
+		value := e.Title
+		if value == "" {
+			value = e.Description
+		}
+		line, _, _ := strings.Cut(value, "\n")
+		fields = append(fields, EmbedField{
+			Name:  truncate(name, maxDigestName),
+			Value: truncate(line, maxDigestValue),
+		})
+	}
+	fields = collapseFields(fields, MaxChangeFields, "+%d more changes")
+
+	title := fmt.Sprintf("🌅 %d updates during quiet hours", len(queued))
+	header := newEmbed()
+	header.Title = title + " (99/99)"
+	overhead := embedChars(header)
+	var pages [][]EmbedField
+	var page []EmbedField
+	chars := overhead
+	for _, f := range fields {
+		size := len([]rune(f.Name)) + len([]rune(f.Value))
+		if len(page) == maxEmbedFields || (len(page) > 0 && chars+size > maxBatchChars) {
+			pages = append(pages, page)
+			page, chars = nil, overhead
+		}
+		page = append(page, f)
+		chars += size
+	}
+	pages = append(pages, page)
+
+	log.Printf("[INFO] Sending quiet hours digest with %d events in %d message(s)", len(queued), len(pages))
+	for i, page := range pages {
+		embed := newEmbed()
+		embed.Color = colorUpdated
+		embed.Title = title
+		if len(pages) > 1 {
+			embed.Title += fmt.Sprintf(" (%d/%d)", i+1, len(pages))
+		}
+		embed.Fields = page
+		sendToDiscord(targets, embed, deliveryContext{})
+	}
+}
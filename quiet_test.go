@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDigestStaysUnderMessageLimit(t *testing.T) {
+	discord := newMockDiscord(t)
+	prev := MaxChangeFields
+	MaxChangeFields = 0
+	t.Cleanup(func() { MaxChangeFields = prev })
+
+	var held []DiscordEmbed
+	for i := 0; i < 40; i++ {
+		e := newEmbed()
+		e.Author.Name = strings.Repeat("a", 300)
+		e.Description = strings.Repeat("long description ", 100)
+		held = append(held, e)
+	}
+	sendDigest(DiscordURLs, held)
+
+	embeds := discord.embeds(t)
+	if len(embeds) < 2 {
+		t.Fatalf("digest sent as %d message(s), want it split", len(embeds))
+	}
+	total := 0
+	for _, e := range embeds {
+		if n := embedChars(e); n > maxBatchChars {
+			t.Errorf("digest message has %d characters, over %d", n, maxBatchChars)
+		}
+		if len(e.Fields) > maxEmbedFields {
+			t.Errorf("digest message has %d fields", len(e.Fields))
+		}
+		total += len(e.Fields)
+	}
+	if total != len(held) {
+		t.Errorf("digest lists %d events, want %d", total, len(held))
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// --- Project Routing ---
+// ROUTING_FILE is a JSON object mapping a project ID or identifier to one or
+// more comma-separated Discord webhook URLs, e.g. {"ENG": "https://discord.com/api/webhooks/..."}.
+// Projects without an entry use DISCORD_WEBHOOK_URL.
+var RoutingFile = getEnv("ROUTING_FILE", "")
+
+// PRIORITY_WEBHOOKS sends issues of a priority elsewhere, e.g. "urgent=https://...|https://...".
+// Priority routes take precedence over project routes.
+var PriorityWebhooks = getEnvMap("PRIORITY_WEBHOOKS")
+
+// Discord role pinged when an issue is created as or raised to urgent
+var UrgentMentionRoleID = getEnv("URGENT_MENTION_ROLE_ID", "")
+
+var (
+	routes   = map[string][]string{}
+	routesMu sync.RWMutex
+)
+
+func loadRouting() (int, error) {
+	if RoutingFile == "" {
+		return 0, nil
+	}
+	raw, err := os.ReadFile(RoutingFile)
+	if err != nil {
+		return 0, err
+	}
+	var entries map[string]string
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return 0, fmt.Errorf("parse %s: %w", RoutingFile, err)
+	}
+
+	table := make(map[string][]string, len(entries))
+	for project, urls := range entries {
+		for _, u := range strings.Split(urls, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				table[strings.ToLower(project)] = append(table[strings.ToLower(project)], u)
+			}
+		}
+	}
+
+	routesMu.Lock()
+	routes = table
+	routesMu.Unlock()
+	return len(table), nil
+}
+
+func setupRouting() {
+	n, err := loadRouting()
+	if err != nil {
+		log.Fatalf("[FATAL] Loading ROUTING_FILE: %v", err)
+	}
+	if RoutingFile != "" {
+		log.Printf("[INFO] Loaded %d project routes from %s", n, RoutingFile)
+	}
+}
+
+// targetsFor returns the webhook URLs for the project an event belongs to.
+func targetsFor(data map[string]interface{}) []string {
+	if prio, ok := data["priority"].(string); ok {
+		if urls := PriorityWebhooks[strings.ToLower(strings.TrimSpace(prio))]; urls != "" {
+			return strings.Split(urls, "|")
+		}
+	}
+
+	keys := []string{projectIDOf(data)}
+	if project, ok := data["project_detail"].(map[string]interface{}); ok {
+		if ident, ok := project["identifier"].(string); ok {
+			keys = append(keys, ident)
+		}
+	}
+
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+	for _, k := range keys {
+		if urls, ok := routes[strings.ToLower(k)]; ok {
+			return urls
+		}
+	}
+	return DiscordURLs
+}
+
+// becameUrgent reports whether an issue event should ping URGENT_MENTION_ROLE_ID:
+// an urgent issue was created, or an update raised its priority to urgent.
+func becameUrgent(data map[string]interface{}, action string, activities []map[string]interface{}) bool {
+	if UrgentMentionRoleID == "" {
+		return false
+	}
+	if action == "created" {
+		prio, _ := data["priority"].(string)
+		return strings.EqualFold(prio, "urgent")
+	}
+	if action == "updated" {
+		for _, a := range activities {
+			if a["field"] == "priority" && strings.EqualFold(fmt.Sprintf("%v", a["new_value"]), "urgent") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTargetsForPriorityMixedCase(t *testing.T) {
+	prevHooks, prevURLs := PriorityWebhooks, DiscordURLs
+	PriorityWebhooks = map[string]string{"urgent": "https://urgent-a|https://urgent-b", "high": "https://high"}
+	DiscordURLs = []string{"https://default"}
+	t.Cleanup(func() { PriorityWebhooks, DiscordURLs = prevHooks, prevURLs })
+
+	tests := map[string][]string{
+		"Urgent":  {"https://urgent-a", "https://urgent-b"},
+		" HIGH ":  {"https://high"},
+		"unknown": {"https://default"},
+	}
+	for prio, want := range tests {
+		got := targetsFor(map[string]interface{}{"priority": prio})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("targetsFor(priority %q) = %v, want %v", prio, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// --- Slack Delivery ---
+// Targets are formatted per platform, so one fan-out can mix Discord and
+// Slack webhooks. Slack is detected from hooks.slack.com or a "slack+" prefix,
+// e.g. DISCORD_WEBHOOK_URL="https://discord.com/api/webhooks/...,slack+https://chat.example.com/hook".
+
+var slackClient = &http.Client{Timeout: 10 * time.Second}
+
+// slackTarget reports whether target is a Slack webhook, returning its URL without the prefix.
+func slackTarget(target string) (string, bool) {
+	if rest, ok := strings.CutPrefix(target, "slack+"); ok {
+		return rest, true
+	}
+	u, err := url.Parse(target)
+	return target, err == nil && u.Host == "hooks.slack.com"
+}
+
+// Slack mrkdwn treats these as control characters in message text
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackMrkdwn escapes text for Slack and turns Discord bold (**x**) into Slack bold (*x*).
+func slackMrkdwn(s string) string {
+	return strings.ReplaceAll(slackEscaper.Replace(s), "**", "*")
+}
+
+// slackText renders an embed as Slack mrkdwn.
+func slackText(embed DiscordEmbed) string {
+	var lines []string
+	if embed.Author != nil && embed.Author.Name != "" {
+		lines = append(lines, "*"+slackEscaper.Replace(embed.Author.Name)+"*")
+	}
+	if embed.Title != "" {
+		title := slackEscaper.Replace(embed.Title)
+		if embed.URL != "" {
+			title = "<" + embed.URL + "|" + title + ">"
+		}
+		lines = append(lines, title)
+	}
+	if embed.Description != "" {
+		lines = append(lines, slackMrkdwn(embed.Description))
+	}
+	for _, f := range embed.Fields {
+		lines = append(lines, "*"+slackEscaper.Replace(f.Name)+":* "+slackMrkdwn(f.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sendSlack posts embeds to a Slack incoming webhook as one message.
+func sendSlack(target string, embeds []DiscordEmbed) {
+	var blocks []string
+	for _, embed := range embeds {
+		blocks = append(blocks, slackText(embed))
+	}
+	body, _ := json.Marshal(map[string]interface{}{"text": strings.Join(blocks, "\n\n")})
+	postTracked(slackClient, "Slack webhook", target, body, slackGone)
+}
+
+// slackGone reports whether Slack rejected the webhook itself: revoked
+// (403), removed (404) or posting to an archived channel (410).
+func slackGone(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
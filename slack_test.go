@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSlackTextEscapes(t *testing.T) {
+	embed := DiscordEmbed{
+		Title:       "Fix a < b & c > d",
+		URL:         "https://plane.so/ws/browse/ENG-1/",
+		Description: "Field **priority** changed.",
+		Fields:      []EmbedField{{Name: "Change", Value: "`<none>` → `High`"}},
+	}
+	want := "<https://plane.so/ws/browse/ENG-1/|Fix a &lt; b &amp; c &gt; d>\n" +
+		"Field *priority* changed.\n" +
+		"*Change:* `&lt;none&gt;` → `High`"
+	if got := slackText(embed); got != want {
+		t.Errorf("slackText = %q, want %q", got, want)
+	}
+}
+
+func TestSlackDisabledWhenGone(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { disabledWebhooks.Delete(srv.URL) })
+
+	failed := metricFailed.Value()
+	sendSlack(srv.URL, []DiscordEmbed{{Title: "Login fails"}})
+	sendSlack(srv.URL, []DiscordEmbed{{Title: "Login fails"}})
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Slack called %d times, want 1 before the webhook is disabled", n)
+	}
+	if got := metricFailed.Value() - failed; got != 1 {
+		t.Errorf("failed deliveries = %d, want 1", got)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// --- Persistence ---
+// STATE_FILE keeps small lookup tables (e.g. forum thread IDs) across restarts.
+// Without it state lives in memory only.
+var (
+	StateFile = getEnv("STATE_FILE", "")
+	StateTTL  = getEnvDuration("STATE_TTL", 30*24*time.Hour) // per-issue entries older than this are pruned; 0 keeps them
+
+	// Writes within this delay are batched into one save of the file
+	StateSaveDelay = getEnvDuration("STATE_SAVE_DELAY", 2*time.Second)
+)
+
+// Buckets holding one entry per issue and target; the others stay small
+var expiringBuckets = map[string]bool{
+	"created":  true,
+	"messages": true,
+}
+
+type stateEntry struct {
+	Value string    `json:"value"`
+	At    time.Time `json:"at"`
+}
+
+// UnmarshalJSON also reads the bare strings of files written before entries
+// carried a timestamp; those count as written at load time.
+func (e *stateEntry) UnmarshalJSON(raw []byte) error {
+	if err := json.Unmarshal(raw, &e.Value); err == nil {
+		e.At = time.Now()
+		return nil
+	}
+	type plain stateEntry
+	return json.Unmarshal(raw, (*plain)(e))
+}
+
+var (
+	state      = map[string]map[string]stateEntry{}
+	stateDirty bool // changed since the last save
+	stateSaves *time.Timer
+	stateMu    sync.Mutex
+)
+
+func loadState() {
+	if StateFile == "" {
+		return
+	}
+	raw, err := os.ReadFile(StateFile)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("[FATAL] Reading STATE_FILE: %v", err)
+	}
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if err := json.Unmarshal(raw, &state); err != nil {
+		log.Fatalf("[FATAL] Parsing STATE_FILE: %v", err)
+	}
+	pruneStateLocked()
+}
+
+func stateGet(bucket, key string) string {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	return state[bucket][key].Value
+}
+
+func stateSet(bucket, key, value string) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if state[bucket] == nil {
+		state[bucket] = map[string]stateEntry{}
+	}
+	state[bucket][key] = stateEntry{Value: value, At: time.Now()}
+	if StateFile == "" {
+		pruneStateLocked()
+		return
+	}
+	stateDirty = true
+	if stateSaves == nil {
+		stateSaves = time.AfterFunc(StateSaveDelay, flushState)
+	}
+}
+
+// flushState saves pending changes to STATE_FILE. Besides the debounced save
+// it runs on shutdown so no write is lost.
+func flushState() {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	stateSaves = nil
+	if !stateDirty {
+		return
+	}
+	stateDirty = false
+	pruneStateLocked()
+	saveStateLocked()
+}
+
+// pruneStateLocked drops expired per-issue entries; stateMu must be held.
+func pruneStateLocked() {
+	if StateTTL <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-StateTTL)
+	for bucket := range expiringBuckets {
+		for key, e := range state[bucket] {
+			if e.At.Before(cutoff) {
+				delete(state[bucket], key)
+			}
+		}
+	}
+}
+
+// saveStateLocked writes the state file atomically; stateMu must be held.
+func saveStateLocked() {
+	if StateFile == "" {
+		return
+	}
+	raw, _ := json.MarshalIndent(state, "", "  ")
+	tmp := StateFile + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		log.Printf("[WARN] Writing state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, StateFile); err != nil {
+		log.Printf("[WARN] Writing state: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatePrunesExpiredIssues(t *testing.T) {
+	stateMu.Lock()
+	state = map[string]map[string]stateEntry{
+		"messages": {"old": {Value: "1/1", At: time.Now().Add(-StateTTL - time.Hour)}},
+		"threads":  {"project": {Value: "2", At: time.Now().Add(-StateTTL - time.Hour)}},
+	}
+	stateMu.Unlock()
+
+	stateSet("messages", "new", "1/2")
+	if stateGet("messages", "old") != "" {
+		t.Error("expired message entry kept")
+	}
+	if stateGet("messages", "new") != "1/2" {
+		t.Error("fresh message entry pruned")
+	}
+	if stateGet("threads", "project") != "2" {
+		t.Error("thread entry pruned")
+	}
+}
+
+func TestLoadStateBareStrings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte(`{"threads": {"project": "2"}}`), 0o600)
+	prev := StateFile
+	StateFile = path
+	t.Cleanup(func() { StateFile = prev })
+
+	loadState()
+	if got := stateGet("threads", "project"); got != "2" {
+		t.Errorf("threads[project] = %q, want 2", got)
+	}
+}
+
+func TestStateSavesBatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	prevFile, prevDelay := StateFile, StateSaveDelay
+	StateFile, StateSaveDelay = path, time.Hour
+	t.Cleanup(func() { StateFile, StateSaveDelay = prevFile, prevDelay })
+
+	stateSet("messages", "a", "1/1")
+	stateSet("messages", "b", "1/2")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("state written before the save delay: %v", err)
+	}
+	stateMu.Lock()
+	stateSaves.Stop()
+	stateMu.Unlock()
+	flushState()
+
+	stateMu.Lock()
+	state = map[string]map[string]stateEntry{}
+	stateMu.Unlock()
+	loadState()
+	if stateGet("messages", "a") != "1/1" || stateGet("messages", "b") != "1/2" {
+		t.Errorf("flushed state = %v", state)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// --- Description Templates ---
+// DESCRIPTION_TEMPLATES_FILE is a JSON object of Go text/template strings keyed
+// by "event.action" or "event", e.g. {"issue.created": "{{.Actor}} filed {{.Data.name}}"}.
+// Templates see .Event, .Action, .Actor, .Data, .Activity, .Payload and
+// .Default, the description the bridge would otherwise use. Events without a
+// template keep the default. Missing keys render empty; {{default "?" .Data.estimate}}
+// substitutes a value for them.
+var DescriptionTemplatesFile = getEnv("DESCRIPTION_TEMPLATES_FILE", "")
+
+var descriptionTemplates = map[string]*template.Template{}
+
+var templateFuncs = template.FuncMap{
+	"truncate": func(s string, n int) string { return truncate(s, n) },
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"date":     formatDate,
+	"default":  templateDefault,
+}
+
+// templateDefault returns v, or fallback when v is missing or empty.
+func templateDefault(fallback string, v interface{}) interface{} {
+	if v == nil || v == "" {
+		return fallback
+	}
+	return v
+}
+
+type templateData struct {
+	Event, Action, Actor, Default string
+	Data, Activity, Payload       map[string]interface{}
+}
+
+// setupTemplates parses every template at startup so mistakes fail fast.
+func setupTemplates() {
+	if DescriptionTemplatesFile == "" {
+		return
+	}
+	raw, err := os.ReadFile(DescriptionTemplatesFile)
+	if err != nil {
+		log.Fatalf("[FATAL] Reading DESCRIPTION_TEMPLATES_FILE: %v", err)
+	}
+	var sources map[string]string
+	if err := json.Unmarshal(raw, &sources); err != nil {
+		log.Fatalf("[FATAL] Parsing DESCRIPTION_TEMPLATES_FILE: %v", err)
+	}
+	for key, src := range sources {
+		t, err := template.New(key).Funcs(templateFuncs).Parse(src)
+		if err != nil {
+			log.Fatalf("[FATAL] Invalid description template %q: %v", key, err)
+		}
+		descriptionTemplates[key] = t
+	}
+	log.Printf("[INFO] Loaded %d description templates", len(descriptionTemplates))
+}
+
+// renderDescription applies the event's template, falling back to the default
+// description when there is none or it fails.
+func renderDescription(d templateData) string {
+	t, ok := descriptionTemplates[d.Event+"."+d.Action]
+	if !ok {
+		if t, ok = descriptionTemplates[d.Event]; !ok {
+			return d.Default
+		}
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, d); err != nil {
+		log.Printf("[WARN] Description template %q failed: %v", t.Name(), err)
+		return d.Default
+	}
+	// Missing map keys print as "<no value>" whatever the missingkey option
+	return truncate(strings.ReplaceAll(buf.String(), "<no value>", ""), 4096)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+	"text/template"
+)
+
+func TestRenderDescriptionMissingKeys(t *testing.T) {
+	tests := map[string]string{
+		"est={{.Data.estimate}}":                  "est=",
+		`est={{default "?" .Data.estimate}}`:      "est=?",
+		`est={{.Data.points | default "?"}}`:      "est=3",
+		`name={{default "?" .Data.name | upper}}`: "name=LOGIN",
+	}
+	for src, want := range tests {
+		descriptionTemplates = map[string]*template.Template{
+			"issue": template.Must(template.New("issue").Funcs(templateFuncs).Parse(src)),
+		}
+		got := renderDescription(templateData{
+			Event: "issue", Action: "created",
+			Data: map[string]interface{}{"name": "login", "points": 3},
+		})
+		if got != want {
+			t.Errorf("%s rendered %q, want %q", src, got, want)
+		}
+	}
+	descriptionTemplates = map[string]*template.Template{}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"strings"
+)
+
+// --- Payload Validation ---
+// With VALIDATE_PAYLOADS the bridge warns when a webhook lacks fields it relies
+// on, which usually means Plane changed its payload format.
+var ValidatePayloads = getEnvBool("VALIDATE_PAYLOADS", false)
+
+// Dotted paths expected per "event" or "event.action"; the more specific entry
+// wins. "activity." paths are checked on every activity however it was sent.
+var requiredFields = map[string][]string{
+	"issue":                 {"data.id", "data.name"},
+	"issue.deleted":         {"data.id"},
+	"issue.updated":         {"data.id", "data.name", "activity.field"},
+	"issue_comment":         {"data.issue", "data.comment_stripped"},
+	"issue_comment.deleted": {"data.id"},
+	"issue_link":            {"data.issue", "data.url"},
+}
+
+// missingFields returns the required paths absent from the payload, or from
+// any of its activities.
+func missingFields(p map[string]interface{}, activities []map[string]interface{}, event, action string) []string {
+	paths, ok := requiredFields[event+"."+action]
+	if !ok {
+		paths = requiredFields[event]
+	}
+	var missing []string
+	for _, path := range paths {
+		if rest, ok := strings.CutPrefix(path, "activity."); ok {
+			if len(activities) == 0 {
+				missing = append(missing, path)
+			}
+			for _, a := range activities {
+				if lookupPath(a, rest) == nil {
+					missing = append(missing, path)
+					break
+				}
+			}
+			continue
+		}
+		if lookupPath(p, path) == nil {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// lookupPath follows a dotted path through nested maps.
+func lookupPath(m map[string]interface{}, path string) interface{} {
+	var cur interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		m, _ := cur.(map[string]interface{})
+		cur = m[key]
+	}
+	return cur
+}
+
+func validatePayload(p map[string]interface{}, activities []map[string]interface{}, event, action string) {
+	if !ValidatePayloads {
+		return
+	}
+	if missing := missingFields(p, activities, event, action); len(missing) > 0 {
+		log.Printf("[WARN] Unexpected payload shape: event=%s action=%s missing=%s", event, action, strings.Join(missing, ","))
+	}
+}
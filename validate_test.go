@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingFieldsActivityShapes(t *testing.T) {
+	data := map[string]interface{}{"id": "issue-1", "name": "Login fails"}
+	tests := []struct {
+		name string
+		p    map[string]interface{}
+		want []string
+	}{
+		{"object", map[string]interface{}{"data": data, "activity": map[string]interface{}{"field": "state"}}, nil},
+		{"array", map[string]interface{}{"data": data, "activity": []interface{}{
+			map[string]interface{}{"field": "state"}, map[string]interface{}{"field": "priority"},
+		}}, nil},
+		{"array missing field", map[string]interface{}{"data": data, "activity": []interface{}{
+			map[string]interface{}{"field": "state"}, map[string]interface{}{},
+		}}, []string{"activity.field"}},
+		{"nested in data", map[string]interface{}{"data": map[string]interface{}{
+			"id": "issue-1", "name": "Login fails", "activity": map[string]interface{}{"field": "state"},
+		}}, nil},
+		{"no activity", map[string]interface{}{"data": data}, []string{"activity.field"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := tt.p["data"].(map[string]interface{})
+			activities := activityList(tt.p["activity"])
+			if len(activities) == 0 {
+				activities = activityList(data["activity"])
+			}
+			if got := missingFields(tt.p, activities, "issue", "updated"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingFields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}